@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Command mesos-exporter serves the metrics of a Mesos cluster in the
+// Prometheus exposition format. On each scrape it fetches the metrics of the
+// leading master and of every agent known to it.
+//
+// e.g.
+//
+// 	mesos-exporter -master http://10.0.0.1:5050,http://10.0.0.2:5050 -listen :9105
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/miroswan/mesops/pkg/v1"
+	"github.com/miroswan/mesops/pkg/v1/exporter"
+)
+
+func main() {
+	var (
+		masters        = flag.String("master", "http://127.0.0.1:5050", "comma separated URLs of the masters")
+		listen         = flag.String("listen", ":9105", "address to serve metrics on")
+		path           = flag.String("path", "/metrics", "path to serve metrics on")
+		timeout        = flag.Duration("timeout", 10*time.Second, "time allowed for a scrape")
+		scrapeAgents   = flag.Bool("scrape-agents", true, "scrape the agents known to the master")
+		maxConcurrency = flag.Int("max-concurrency", 8, "maximum number of masters and agents scraped at the same time")
+		caFile         = flag.String("ca-cert", "", "PEM encoded CA bundle used to verify the masters and agents")
+		certFile       = flag.String("cert", "", "PEM encoded client certificate")
+		keyFile        = flag.String("key", "", "PEM encoded client key")
+		principal      = flag.String("principal", "", "principal used for HTTP Basic authentication")
+		secretFile     = flag.String("secret-file", "", "file holding the secret used for HTTP Basic authentication")
+	)
+	flag.Parse()
+
+	var urls []string = strings.Split(*masters, ",")
+	var clientMetrics *v1.ClientMetrics = v1.NewClientMetrics()
+	var b *v1.MasterBuilder = v1.NewMasterBuilder(urls[0], urls[1:]...).
+		SetHTTPClient(&http.Client{Timeout: *timeout}).
+		SetClientMetrics(clientMetrics)
+	if *caFile != "" {
+		b.SetCACertFile(*caFile)
+	}
+	if *certFile != "" || *keyFile != "" {
+		b.SetClientCertFiles(*certFile, *keyFile)
+	}
+	if *principal != "" {
+		secret, err := ioutil.ReadFile(*secretFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b.SetBasicAuth(*principal, strings.TrimSpace(string(secret)))
+	}
+	master, err := b.Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	collector, err := exporter.NewCollectorBuilder(master).
+		SetTimeout(*timeout).
+		SetScrapeAgents(*scrapeAgents).
+		SetMaxConcurrency(*maxConcurrency).
+		Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var registry *prometheus.Registry = prometheus.NewRegistry()
+	registry.MustRegister(collector, clientMetrics)
+	http.Handle(*path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: log.Default()}))
+	log.Printf("serving metrics of %s on %s%s", *masters, *listen, *path)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
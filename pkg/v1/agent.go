@@ -23,13 +23,13 @@
 package v1
 
 import (
-	"bytes"
 	"context"
-	"io"
 	"net/http"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/mesos/go-proto/mesos/v1/agent"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Agent is a struct that handles most interactions with the
@@ -64,10 +64,173 @@ func (b *AgentBuilder) SetHTTPClient(httpclient *http.Client) *AgentBuilder {
 	return b
 }
 
+// SetEncoding sets the wire encoding of calls, responses and streamed records
+// and returns a pointer to the AgentBuilder. If SetEncoding is not called, it
+// will be set to EncodingProtobuf.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetEncoding(EncodingJSON)
+func (b *AgentBuilder) SetEncoding(encoding Encoding) *AgentBuilder {
+	b.clientBuilder.setEncoding(encoding)
+	return b
+}
+
+// SetBasicAuth sets the principal and secret sent with each request using
+// HTTP Basic authentication and returns a pointer to the AgentBuilder. Use it
+// when the agent is started with --authenticate_http_readwrite or
+// --authenticate_http_readonly. SetBasicAuth cannot be combined with
+// SetCredentialProvider.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetBasicAuth("principal", "secret")
+func (b *AgentBuilder) SetBasicAuth(principal string, secret string) *AgentBuilder {
+	b.clientBuilder.setBasicAuth(principal, secret)
+	return b
+}
+
+// SetCredentialProvider sets the CredentialProvider that supplies the bearer
+// token sent with each request and returns a pointer to the AgentBuilder. A
+// request rejected with a 401 is sent once more after the token is refreshed.
+// SetCredentialProvider cannot be combined with SetBasicAuth.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetCredentialProvider(myProvider)
+func (b *AgentBuilder) SetCredentialProvider(credentialProvider CredentialProvider) *AgentBuilder {
+	b.clientBuilder.setCredentialProvider(credentialProvider)
+	return b
+}
+
+// SetCACertFile sets the path of the PEM encoded CA bundle used to verify the
+// agent's certificate and returns a pointer to the AgentBuilder. If
+// SetCACertFile is not called, the host's root CA set is used. The file is
+// reloaded when it changes on disk.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetCACertFile("/etc/mesos/ca.pem")
+func (b *AgentBuilder) SetCACertFile(caFile string) *AgentBuilder {
+	b.clientBuilder.setCACertFile(caFile)
+	return b
+}
+
+// SetClientCertFiles sets the paths of the PEM encoded certificate and key
+// presented to the agent for mutual TLS and returns a pointer to the
+// AgentBuilder. The files are reloaded when they change on disk.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetClientCertFiles("/etc/mesos/client.pem", "/etc/mesos/client-key.pem")
+func (b *AgentBuilder) SetClientCertFiles(certFile string, keyFile string) *AgentBuilder {
+	b.clientBuilder.setClientCertFiles(certFile, keyFile)
+	return b
+}
+
+// SetServerName sets the name used to verify the agent's certificate and
+// returns a pointer to the AgentBuilder. If SetServerName is not called, the
+// host of the server URL is used.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://10.0.0.1:5051").SetServerName("agent.mesos")
+func (b *AgentBuilder) SetServerName(serverName string) *AgentBuilder {
+	b.clientBuilder.setServerName(serverName)
+	return b
+}
+
+// SetRetryPolicy sets the RetryPolicy that decides whether a failed call is
+// sent again and returns a pointer to the AgentBuilder. If SetRetryPolicy is not
+// called, the policy described by SetMaxRetries is used.
+//
+// e.g.
+//
+// 	var backoff RetryPolicy
+// 	backoff, err = NewExponentialBackoffBuilder().SetMaxRetries(5).Build()
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetRetryPolicy(
+// 		IdempotentOnly(WithMaxElapsedTime(backoff, 30*time.Second)),
+// 	)
+func (b *AgentBuilder) SetRetryPolicy(retryPolicy RetryPolicy) *AgentBuilder {
+	b.clientBuilder.setRetryPolicy(retryPolicy)
+	return b
+}
+
+// SetAttemptHook sets a function that is called with the outcome of every
+// attempt of every call, successful or not, and returns a pointer to the
+// AgentBuilder. Use it to log or count retries. The hook is called on the
+// goroutine making the call and must not block.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetAttemptHook(func(attempt Attempt) {
+// 		if attempt.Err != nil {
+// 			log.Printf("%s attempt %d failed: %s", attempt.CallType, attempt.Number, attempt.Err)
+// 		}
+// 	})
+func (b *AgentBuilder) SetAttemptHook(attemptHook func(attempt Attempt)) *AgentBuilder {
+	b.clientBuilder.setAttemptHook(attemptHook)
+	return b
+}
+
+// SetInterceptors sets the Interceptors that run around every call and
+// returns a pointer to the AgentBuilder. The first Interceptor is the
+// outermost. If SetInterceptors is not called, calls are sent as they are.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetInterceptors(logCalls, auditCalls)
+func (b *AgentBuilder) SetInterceptors(interceptors ...Interceptor) *AgentBuilder {
+	b.clientBuilder.setInterceptors(interceptors...)
+	return b
+}
+
+// SetTracerProvider enables OpenTelemetry tracing with the given
+// trace.TracerProvider and returns a pointer to the AgentBuilder. Each call
+// produces a client span named after its Call_Type, e.g. GET_HEALTH, with
+// attributes for the endpoint, the retry count, the HTTP status and the
+// response size, and the trace context is propagated through the request
+// headers. If SetTracerProvider is not called, calls are not traced.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetTracerProvider(otel.GetTracerProvider())
+func (b *AgentBuilder) SetTracerProvider(tracerProvider trace.TracerProvider) *AgentBuilder {
+	b.clientBuilder.setTracerProvider(tracerProvider)
+	return b
+}
+
+// SetPropagator sets the propagation.TextMapPropagator that writes the trace
+// context into the request headers and returns a pointer to the AgentBuilder.
+// If SetPropagator is not called, the global propagator returned by
+// otel.GetTextMapPropagator is used.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetPropagator(propagation.TraceContext{})
+func (b *AgentBuilder) SetPropagator(propagator propagation.TextMapPropagator) *AgentBuilder {
+	b.clientBuilder.setPropagator(propagator)
+	return b
+}
+
+// SetClientMetrics sets the ClientMetrics that record the calls made by the
+// Agent and returns a pointer to the AgentBuilder. If SetClientMetrics is not
+// called, no metrics are recorded.
+//
+// e.g.
+//
+// 	var b *AgentBuilder = NewAgentBuilder("https://127.0.0.1:5051").SetClientMetrics(metrics)
+func (b *AgentBuilder) SetClientMetrics(metrics *ClientMetrics) *AgentBuilder {
+	b.clientBuilder.setClientMetrics(metrics)
+	return b
+}
+
 // SetMaxRetries sets maxRetries for the Agent and returns a pointer to an
 // AgentBuilder. If SetMaxRetries is not called, it will be set to 10.
 // Each HTTP request will retry up to the provided value upon failure.
-// Binary exponential backoff is implemented as specified by RFC2616
+// Binary exponential backoff is implemented as specified by RFC2616. Only calls
+// that are safe to send more than once are retried, see IdempotentOnly.
+// SetMaxRetries has no effect if SetRetryPolicy is called.
 //
 // e.g.
 //
@@ -80,6 +243,7 @@ func (b *AgentBuilder) SetMaxRetries(maxRetries int) *AgentBuilder {
 // Build returns a pointer to a constructed Agent.
 func (b *AgentBuilder) Build() (a *Agent, err error) {
 	var client *client
+	b.clientBuilder.client.target = targetAgent
 	client, err = b.clientBuilder.build()
 	if err != nil {
 		return
@@ -88,20 +252,14 @@ func (b *AgentBuilder) Build() (a *Agent, err error) {
 	return
 }
 
-// sendSimpleCall configures a simple mesos_v1_agent.Call, marshalls it into binary format,
+// sendSimpleCall configures a simple mesos_v1_agent.Call, marshalls it into the configured encoding,
 // and sends it over HTTP to the configured agent. These calls don't need
 // additional configuration other than the mesos_v1_agent.Call_Type
 func (a *Agent) sendSimpleCall(ctx context.Context, callType mesos_v1_agent.Call_Type) (
 	response *mesos_v1_agent.Response, httpResponse *http.Response, err error,
 ) {
 	var callMsg proto.Message = &mesos_v1_agent.Call{Type: &callType}
-	var b []byte
-	b, err = proto.Marshal(callMsg)
-	if err != nil {
-		return
-	}
-	var buf io.Reader = bytes.NewBuffer(b)
 	response = &mesos_v1_agent.Response{}
-	httpResponse, err = a.client.doProtoWrapper(ctx, buf, response)
+	httpResponse, err = a.client.makeCall(ctx, callMsg, response)
 	return
 }
@@ -26,13 +26,63 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/mesos/go-proto/mesos/v1/master"
 )
 
 // GetAgents  retrieves information about all the mesos_v1_agents known to the mesos_v1_master.
+// On masters that support draining, each agent also reports whether it is
+// deactivated, its DrainInfo while it is draining or drained, and the
+// estimated time the drain starts if the agent is in a maintenance window.
 func (m *Master) GetAgents(ctx context.Context) (response *mesos_v1_master.Response, err error) {
 	var httpResponse *http.Response
 	response, httpResponse, err = m.sendSimpleCall(ctx, mesos_v1_master.Call_GET_AGENTS)
 	defer httpResponse.Body.Close()
 	return
 }
+
+// DrainAgent deactivates an agent and kills all of its tasks. The agent stays
+// DRAINING until the terminal status updates of its tasks are acknowledged,
+// then becomes DRAINED. If MaxGracePeriod is set, it caps the kill grace
+// period of the tasks. If MarkGone is set, the agent is marked gone once it is
+// drained. A drained agent receives no offers until it is reactivated with
+// ReactivateAgent.
+func (m *Master) DrainAgent(ctx context.Context, call *mesos_v1_master.Call_DrainAgent) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_DRAIN_AGENT
+	var message proto.Message = &mesos_v1_master.Call{
+		Type:       &callType,
+		DrainAgent: call,
+	}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// DeactivateAgent stops the master from sending offers for the resources of an
+// agent. Tasks already running on the agent are not affected.
+func (m *Master) DeactivateAgent(ctx context.Context, call *mesos_v1_master.Call_DeactivateAgent) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_DEACTIVATE_AGENT
+	var message proto.Message = &mesos_v1_master.Call{
+		Type:            &callType,
+		DeactivateAgent: call,
+	}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// ReactivateAgent resumes offers for the resources of an agent that was
+// deactivated or drained. It fails while the agent is still DRAINING.
+func (m *Master) ReactivateAgent(ctx context.Context, call *mesos_v1_master.Call_ReactivateAgent) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_REACTIVATE_AGENT
+	var message proto.Message = &mesos_v1_master.Call{
+		Type:            &callType,
+		ReactivateAgent: call,
+	}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
@@ -49,3 +49,119 @@ func TestGetAgents(t *testing.T) {
 		t.Errorf("Expected true: got %b", data.GetAgents.Agents[0].GetActive())
 	}
 }
+
+func TestGetAgentsDrainStatus(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	// Setup Response
+	active := false
+	deactivated := true
+	hostname := "test"
+	responseType := mesos_v1_master.Response_GET_AGENTS
+	version := "1.9.0"
+	state := mesos_v1.DrainState_DRAINING
+	markGone := true
+	gracePeriod := int64(60000000000)
+	response := &mesos_v1_master.Response{
+		Type: &responseType,
+		GetAgents: &mesos_v1_master.Response_GetAgents{
+			Agents: []*mesos_v1_master.Response_GetAgents_Agent{
+				&mesos_v1_master.Response_GetAgents_Agent{
+					Active:      &active,
+					Deactivated: &deactivated,
+					Version:     &version,
+					AgentInfo: &mesos_v1.AgentInfo{
+						Hostname: &hostname,
+					},
+					DrainInfo: &mesos_v1.DrainInfo{
+						State: &state,
+						Config: &mesos_v1.DrainConfig{
+							MaxGracePeriod: &mesos_v1.DurationInfo{Nanoseconds: &gracePeriod},
+							MarkGone:       &markGone,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	output, err := proto.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup Handler
+	s.SetOutput(output).Handle()
+
+	// Call
+	data, err := s.Master().GetAgents(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := data.GetGetAgents().GetAgents()[0]
+	if !agent.GetDeactivated() {
+		t.Error("Expected the agent to be deactivated")
+	}
+	if agent.GetDrainInfo().GetState() != mesos_v1.DrainState_DRAINING {
+		t.Errorf("Expected DRAINING: got %s", agent.GetDrainInfo().GetState())
+	}
+	if !agent.GetDrainInfo().GetConfig().GetMarkGone() {
+		t.Error("Expected mark_gone to be true")
+	}
+}
+
+func TestDrainAgent(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := "test-agent"
+	gracePeriod := int64(60000000000)
+	markGone := true
+	call := &mesos_v1_master.Call_DrainAgent{
+		AgentId:        &mesos_v1.AgentID{Value: &agentID},
+		MaxGracePeriod: &mesos_v1.DurationInfo{Nanoseconds: &gracePeriod},
+		MarkGone:       &markGone,
+	}
+
+	err := s.Master().DrainAgent(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeactivateAgent(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := "test-agent"
+	call := &mesos_v1_master.Call_DeactivateAgent{
+		AgentId: &mesos_v1.AgentID{Value: &agentID},
+	}
+
+	err := s.Master().DeactivateAgent(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReactivateAgent(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := "test-agent"
+	call := &mesos_v1_master.Call_ReactivateAgent{
+		AgentId: &mesos_v1.AgentID{Value: &agentID},
+	}
+
+	err := s.Master().ReactivateAgent(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,61 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"net/http"
+)
+
+// CredentialProvider supplies the bearer tokens used to authenticate with the
+// Mesos Operator API, such as those issued by an identity provider in front of
+// Mesos. Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	// Token returns the token to send with a request.
+	Token(ctx context.Context) (token string, err error)
+	// Refresh is called when a request was rejected with a 401, usually
+	// because the token has expired. Calls to Token that follow should return
+	// the new token.
+	Refresh(ctx context.Context) (err error)
+}
+
+// basicAuth holds the credentials for HTTP Basic authentication.
+type basicAuth struct {
+	principal string
+	secret    string
+}
+
+// authorize adds the configured credentials, if any, to the request.
+func (c *client) authorize(ctx context.Context, req *http.Request) (err error) {
+	if c.basicAuth != nil {
+		req.SetBasicAuth(c.basicAuth.principal, c.basicAuth.secret)
+	}
+	if c.credentialProvider != nil {
+		var token string
+		token, err = c.credentialProvider.Token(ctx)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// testCredentialProvider hands out token-1, token-2, ... advancing on Refresh.
+type testCredentialProvider struct {
+	mu        sync.Mutex
+	refreshes int
+}
+
+func (p *testCredentialProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return "token-" + string('1'+rune(p.refreshes)), nil
+}
+
+func (p *testCredentialProvider) Refresh(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshes++
+	return nil
+}
+
+// newAuthServer returns a server that accepts only requests authorized with
+// the given Authorization header value.
+func newAuthServer(t *testing.T, authorization string, hits *int32) *httptest.Server {
+	output := healthyOutput(t)
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if req.Header.Get("Authorization") != authorization {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Write(output)
+	}))
+}
+
+func TestMasterBasicAuth(t *testing.T) {
+	var hits int32
+	// base64("principal:secret")
+	server := newAuthServer(t, "Basic cHJpbmNpcGFsOnNlY3JldA==", &hits)
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetBasicAuth("principal", "secret").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+}
+
+func TestAgentCredentialProviderRefreshesOnUnauthorized(t *testing.T) {
+	var hits int32
+	server := newAuthServer(t, "Bearer token-2", &hits)
+	defer server.Close()
+
+	p := &testCredentialProvider{}
+	a, err := NewAgentBuilder(server.URL).SetCredentialProvider(p).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := a.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+	if p.refreshes != 1 {
+		t.Errorf("expected 1 refresh, got %d", p.refreshes)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("expected 2 requests, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestMasterCredentialProviderRefreshesOnlyOnce(t *testing.T) {
+	var hits int32
+	server := newAuthServer(t, "Bearer never", &hits)
+	defer server.Close()
+
+	p := &testCredentialProvider{}
+	m, err := NewMasterBuilder(server.URL).SetCredentialProvider(p).SetMaxRetries(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.GetHealth(context.Background())
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	httpErr, ok := err.(HTTPError)
+	if !ok {
+		t.Fatalf("expected an HTTPError, got %T", err)
+	}
+	if httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected %d, got %d", http.StatusUnauthorized, httpErr.StatusCode)
+	}
+	if p.refreshes != 1 {
+		t.Errorf("expected 1 refresh, got %d", p.refreshes)
+	}
+}
+
+func TestBasicAuthAndCredentialProviderConflict(t *testing.T) {
+	_, err := NewMasterBuilder("http://127.0.0.1:5050").
+		SetBasicAuth("principal", "secret").
+		SetCredentialProvider(&testCredentialProvider{}).
+		Build()
+	if err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
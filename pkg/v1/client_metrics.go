@@ -0,0 +1,185 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The values of the target label of ClientMetrics.
+const (
+	targetMaster string = "master"
+	targetAgent  string = "agent"
+)
+
+// The values of the outcome label of ClientMetrics.
+const (
+	outcomeSuccess        string = "success"
+	outcomeClientError    string = "client_error"
+	outcomeServerError    string = "server_error"
+	outcomeTransportError string = "transport_error"
+	outcomeCanceled       string = "canceled"
+)
+
+// ClientMetrics records Prometheus metrics about the calls that Masters and
+// Agents make to the Mesos Operator API. It implements prometheus.Collector;
+// register it once and share it between the builders with SetClientMetrics.
+//
+// The metrics are:
+//
+// 	mesops_calls_total{call_type, target, outcome}
+// 	mesops_call_duration_seconds{call_type, target, outcome}
+// 	mesops_call_retries_total{call_type, target}
+// 	mesops_open_streams{call_type, target}
+// 	mesops_events_received_total{event_type}
+//
+// The target label is master or agent. The outcome label is success,
+// client_error for responses with a 4xx status, server_error for other
+// responses outside of the 200 range, transport_error when no response was
+// received and canceled when the context was done first. The open streams
+// gauge counts the event streams of Subscribe and the ProcessIO streams of
+// the container calls.
+//
+// e.g.
+//
+// 	var metrics *ClientMetrics = NewClientMetrics()
+// 	prometheus.MustRegister(metrics)
+// 	var b *MasterBuilder = NewMasterBuilder("http://127.0.0.1:5050").SetClientMetrics(metrics)
+type ClientMetrics struct {
+	calls    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+	streams  *prometheus.GaugeVec
+	events   *prometheus.CounterVec
+}
+
+// NewClientMetrics returns a pointer to a ClientMetrics.
+func NewClientMetrics() *ClientMetrics {
+	return &ClientMetrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mesops",
+			Name:      "calls_total",
+			Help:      "Number of calls made to the Mesos Operator API.",
+		}, []string{"call_type", "target", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mesops",
+			Name:      "call_duration_seconds",
+			Help:      "Duration of calls made to the Mesos Operator API, including retries.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"call_type", "target", "outcome"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mesops",
+			Name:      "call_retries_total",
+			Help:      "Number of times a call to the Mesos Operator API was sent again.",
+		}, []string{"call_type", "target"}),
+		streams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "mesops",
+			Name:      "open_streams",
+			Help:      "Number of open event and ProcessIO streams.",
+		}, []string{"call_type", "target"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mesops",
+			Name:      "events_received_total",
+			Help:      "Number of master events received.",
+		}, []string{"event_type"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *ClientMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.calls.Describe(ch)
+	m.duration.Describe(ch)
+	m.retries.Describe(ch)
+	m.streams.Describe(ch)
+	m.events.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *ClientMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.calls.Collect(ch)
+	m.duration.Collect(ch)
+	m.retries.Collect(ch)
+	m.streams.Collect(ch)
+	m.events.Collect(ch)
+}
+
+// The methods below are called by the client and do nothing on a nil
+// ClientMetrics, so that metrics are optional.
+
+// observeCall records a call that took duration and ended with err.
+func (m *ClientMetrics) observeCall(callType string, target string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	var outcome string = outcomeOf(err)
+	m.calls.WithLabelValues(callType, target, outcome).Inc()
+	m.duration.WithLabelValues(callType, target, outcome).Observe(duration.Seconds())
+}
+
+// observeRetry records that a call is sent again.
+func (m *ClientMetrics) observeRetry(callType string, target string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(callType, target).Inc()
+}
+
+// openStream records that a stream was opened and returns a function that
+// records that it was closed.
+func (m *ClientMetrics) openStream(callType string, target string) (closeStream func()) {
+	if m == nil {
+		return func() {}
+	}
+	var gauge prometheus.Gauge = m.streams.WithLabelValues(callType, target)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// observeEvent records a master event of the given type.
+func (m *ClientMetrics) observeEvent(eventType string) {
+	if m == nil {
+		return
+	}
+	m.events.WithLabelValues(eventType).Inc()
+}
+
+// outcomeOf returns the value of the outcome label for a call that ended with
+// err.
+func outcomeOf(err error) string {
+	var httpError HTTPError
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return outcomeCanceled
+	case errors.As(err, &httpError) && httpError.StatusCode >= 400 && httpError.StatusCode < 500:
+		return outcomeClientError
+	case errors.As(err, &httpError):
+		return outcomeServerError
+	default:
+		return outcomeTransportError
+	}
+}
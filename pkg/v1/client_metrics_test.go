@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClientMetricsCalls(t *testing.T) {
+	output := healthyOutput(t)
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			// Dropped, then retried
+			conn, _, _ := rw.(http.Hijacker).Hijack()
+			conn.Close()
+		case 2:
+			rw.Write(output)
+		default:
+			rw.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewClientMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics)
+	m, err := NewMasterBuilder(server.URL).SetClientMetrics(metrics).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = m.sendSimpleCall(context.Background(), mesos_v1_master.Call_GET_HEALTH); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	if v := testutil.ToFloat64(metrics.calls.WithLabelValues("GET_HEALTH", "master", "success")); v != 1 {
+		t.Errorf("expected 1 successful call, got %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.calls.WithLabelValues("GET_HEALTH", "master", "client_error")); v != 1 {
+		t.Errorf("expected 1 failed call, got %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.retries.WithLabelValues("GET_HEALTH", "master")); v != 1 {
+		t.Errorf("expected 1 retry, got %v", v)
+	}
+	if n := testutil.CollectAndCount(metrics, "mesops_call_duration_seconds"); n != 2 {
+		t.Errorf("expected 2 duration histograms, got %d", n)
+	}
+}
+
+func TestClientMetricsOutcomes(t *testing.T) {
+	tests := map[error]string{
+		nil:                        "success",
+		HTTPError{StatusCode: 400}: "client_error",
+		HTTPError{StatusCode: 503}: "server_error",
+		context.Canceled:           "canceled",
+		RetriesExhaustedError{Err: context.DeadlineExceeded}: "canceled",
+		RetriesExhaustedError{Err: http.ErrHandlerTimeout}:   "transport_error",
+	}
+	for err, outcome := range tests {
+		if got := outcomeOf(err); got != outcome {
+			t.Errorf("expected %s for %v, got %s", outcome, err, got)
+		}
+	}
+}
+
+func TestClientMetricsStreams(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(t, rw, subscribedEvent(), agentRemovedEvent("agent-1"), agentRemovedEvent("agent-2"))
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	metrics := NewClientMetrics()
+	m, err := NewMasterBuilder(server.URL).SetClientMetrics(metrics).SetMaxMissedHeartbeats(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	es := make(EventStream)
+	errChan := make(chan error, 1)
+	go func() { errChan <- m.Subscribe(ctx, es) }()
+	for i := 0; i < 3; i++ {
+		<-es
+	}
+
+	if v := testutil.ToFloat64(metrics.streams.WithLabelValues("SUBSCRIBE", "master")); v != 1 {
+		t.Errorf("expected 1 open stream, got %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.events.WithLabelValues("AGENT_REMOVED")); v != 2 {
+		t.Errorf("expected 2 AGENT_REMOVED events, got %v", v)
+	}
+	if v := testutil.ToFloat64(metrics.events.WithLabelValues("SUBSCRIBED")); v != 1 {
+		t.Errorf("expected 1 SUBSCRIBED event, got %v", v)
+	}
+
+	cancel()
+	select {
+	case <-errChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe did not return")
+	}
+	if v := testutil.ToFloat64(metrics.streams.WithLabelValues("SUBSCRIBE", "master")); v != 0 {
+		t.Errorf("expected no open streams, got %v", v)
+	}
+}
+
+func TestClientMetricsInheritedByAgents(t *testing.T) {
+	metrics := NewClientMetrics()
+	m, err := NewMasterBuilder("http://127.0.0.1:5050").SetClientMetrics(metrics).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname := "agent.mesos"
+	a, err := m.NewAgentBuilder(&mesos_v1.AgentInfo{Hostname: &hostname}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.metrics != metrics || a.target != "agent" {
+		t.Errorf("expected the agent to record to the master's metrics as an agent, got %s", a.target)
+	}
+}
@@ -0,0 +1,290 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// AgentFunc is a call that a Cluster runs on each agent. The ctx carries the
+// per-agent timeout.
+//
+// e.g.
+//
+// 	var getHealth AgentFunc = func(ctx context.Context, agent AgentAPI) (*mesos_v1_agent.Response, error) {
+// 		return agent.GetHealth(ctx)
+// 	}
+type AgentFunc func(ctx context.Context, agent AgentAPI) (response *mesos_v1_agent.Response, err error)
+
+// AgentFilter selects the agents that a Cluster runs a call on.
+type AgentFilter func(agent *mesos_v1_master.Response_GetAgents_Agent) bool
+
+// ActiveAgents is an AgentFilter that selects the active agents.
+func ActiveAgents(agent *mesos_v1_master.Response_GetAgents_Agent) bool {
+	return agent.GetActive()
+}
+
+// AgentResult is the outcome of a call run by a Cluster on one agent.
+type AgentResult struct {
+	// Agent is the agent as reported by the master.
+	Agent *mesos_v1_master.Response_GetAgents_Agent
+	// Response is the response of the agent, if the call succeeded.
+	Response *mesos_v1_agent.Response
+	// Err is the error of the call on the agent.
+	Err error
+}
+
+// AgentResults are the outcomes of a call run by a Cluster, in the order the
+// agents were reported by the master.
+type AgentResults []AgentResult
+
+// Succeeded returns the results of the agents the call succeeded on.
+func (r AgentResults) Succeeded() (succeeded AgentResults) {
+	for _, result := range r {
+		if result.Err == nil {
+			succeeded = append(succeeded, result)
+		}
+	}
+	return
+}
+
+// Failed returns the results of the agents the call failed on.
+func (r AgentResults) Failed() (failed AgentResults) {
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return
+}
+
+// Err returns a ClusterError if the call failed on any agent, or nil.
+func (r AgentResults) Err() error {
+	var failed AgentResults = r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	var e ClusterError = ClusterError{Errors: make(map[string]error, len(failed)), Total: len(r)}
+	for _, result := range failed {
+		e.Errors[result.Agent.GetAgentInfo().GetId().GetValue()] = result.Err
+	}
+	return e
+}
+
+// Cluster runs calls on the agents of a Mesos cluster. It discovers the
+// agents through the master and builds their Agent clients with the settings
+// of the master, see Master.NewAgentBuilder. The clients are reused across
+// calls. Build a Cluster with a ClusterBuilder.
+type Cluster struct {
+	master         *Master
+	maxConcurrency int
+	agentTimeout   time.Duration
+	configureAgent func(b *AgentBuilder)
+
+	mu     sync.Mutex
+	agents map[string]*Agent
+}
+
+// ClusterBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct. Create a ClusterBuilder with
+// NewClusterBuilder
+type ClusterBuilder struct {
+	cluster *Cluster
+}
+
+// NewClusterBuilder returns a pointer to a ClusterBuilder for the cluster led
+// by the given Master.
+func NewClusterBuilder(master *Master) *ClusterBuilder {
+	return &ClusterBuilder{
+		cluster: &Cluster{
+			master:         master,
+			maxConcurrency: 16,
+			agentTimeout:   10 * time.Second,
+			agents:         map[string]*Agent{},
+		},
+	}
+}
+
+// SetMaxConcurrency sets the maximum number of agents a call runs on at the
+// same time and returns a pointer to the ClusterBuilder. If SetMaxConcurrency
+// is not called, it will be set to 16.
+//
+// e.g.
+//
+// 	var b *ClusterBuilder = NewClusterBuilder(m).SetMaxConcurrency(64)
+func (b *ClusterBuilder) SetMaxConcurrency(maxConcurrency int) *ClusterBuilder {
+	b.cluster.maxConcurrency = maxConcurrency
+	return b
+}
+
+// SetAgentTimeout sets the time allowed for a call on each agent and returns a
+// pointer to the ClusterBuilder. A value of 0 disables the per-agent timeout.
+// If SetAgentTimeout is not called, it will be set to 10 seconds.
+//
+// e.g.
+//
+// 	var b *ClusterBuilder = NewClusterBuilder(m).SetAgentTimeout(2 * time.Second)
+func (b *ClusterBuilder) SetAgentTimeout(agentTimeout time.Duration) *ClusterBuilder {
+	b.cluster.agentTimeout = agentTimeout
+	return b
+}
+
+// SetConfigureAgent sets a function that adjusts the AgentBuilder of each
+// agent before its Agent is built and returns a pointer to the ClusterBuilder.
+// Use it for settings that differ between the master and the agents. If
+// SetConfigureAgent is not called, the agents use the settings of the master.
+//
+// e.g.
+//
+// 	var b *ClusterBuilder = NewClusterBuilder(m).SetConfigureAgent(func(b *AgentBuilder) {
+// 		b.SetCredentialProvider(agentCredentials)
+// 	})
+func (b *ClusterBuilder) SetConfigureAgent(configureAgent func(b *AgentBuilder)) *ClusterBuilder {
+	b.cluster.configureAgent = configureAgent
+	return b
+}
+
+// Build returns a pointer to a constructed Cluster.
+func (b *ClusterBuilder) Build() (c *Cluster, err error) {
+	if b.cluster.master == nil {
+		err = errors.New("master must not be nil")
+		return
+	}
+	if b.cluster.maxConcurrency < 1 {
+		err = errors.New("maxConcurrency must be at least 1")
+		return
+	}
+	if b.cluster.agentTimeout < 0 {
+		err = errors.New("agentTimeout must not be negative")
+		return
+	}
+	c = b.cluster
+	return
+}
+
+// Agents returns the agents known to the master that are selected by filter.
+// A nil filter selects every agent.
+func (c *Cluster) Agents(ctx context.Context, filter AgentFilter) (
+	agents []*mesos_v1_master.Response_GetAgents_Agent, err error,
+) {
+	var response *mesos_v1_master.Response
+	response, err = c.master.GetAgents(ctx)
+	if err != nil {
+		return
+	}
+	for _, agent := range response.GetGetAgents().GetAgents() {
+		if filter == nil || filter(agent) {
+			agents = append(agents, agent)
+		}
+	}
+	return
+}
+
+// Agent returns the Agent client for the agent with the given AgentInfo.
+func (c *Cluster) Agent(agentInfo *mesos_v1.AgentInfo) (a *Agent, err error) {
+	var b *AgentBuilder = c.master.NewAgentBuilder(agentInfo)
+	var key string = b.clientBuilder.serverURLs[0]
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a = c.agents[key]
+	if a != nil {
+		return
+	}
+	if c.configureAgent != nil {
+		c.configureAgent(b)
+	}
+	a, err = b.Build()
+	if err != nil {
+		return
+	}
+	c.agents[key] = a
+	return
+}
+
+// Run runs call on the agents selected by filter, at most maxConcurrency at a
+// time, and returns the result of each agent. A nil filter selects every
+// agent. The error is only set if the agents could not be listed; use
+// AgentResults.Err to check whether the call failed on some agents.
+//
+// e.g.
+//
+// 	var results AgentResults
+// 	results, err = cluster.Run(ctx, ActiveAgents, func(ctx context.Context, agent AgentAPI) (*mesos_v1_agent.Response, error) {
+// 		return agent.GetContainers(ctx)
+// 	})
+// 	for _, result := range results.Succeeded() {
+// 		fmt.Println(result.Agent.GetAgentInfo().GetHostname(), len(result.Response.GetGetContainers().GetContainers()))
+// 	}
+func (c *Cluster) Run(ctx context.Context, filter AgentFilter, call AgentFunc) (results AgentResults, err error) {
+	var agents []*mesos_v1_master.Response_GetAgents_Agent
+	agents, err = c.Agents(ctx, filter)
+	if err != nil {
+		return
+	}
+	results = make(AgentResults, len(agents))
+	var wg sync.WaitGroup
+	var sem chan struct{} = make(chan struct{}, c.maxConcurrency)
+	for i, agent := range agents {
+		results[i].Agent = agent
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(result *AgentResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result.Response, result.Err = c.run(ctx, result.Agent.GetAgentInfo(), call)
+		}(&results[i])
+	}
+	wg.Wait()
+	return
+}
+
+// run runs call on one agent within the per-agent timeout.
+func (c *Cluster) run(ctx context.Context, agentInfo *mesos_v1.AgentInfo, call AgentFunc) (
+	response *mesos_v1_agent.Response, err error,
+) {
+	var a *Agent
+	a, err = c.Agent(agentInfo)
+	if err != nil {
+		return
+	}
+	if c.agentTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.agentTimeout)
+		defer cancel()
+	}
+	response, err = call(ctx, a)
+	return
+}
@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newAgentsServer returns a master that reports an agent with the given ID for
+// each of the servers.
+func newAgentsServer(t *testing.T, agents map[string]*httptest.Server, inactive ...string) *httptest.Server {
+	var list []*mesos_v1_master.Response_GetAgents_Agent
+	for id, server := range agents {
+		host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		active := true
+		for _, inactiveID := range inactive {
+			if inactiveID == id {
+				active = false
+			}
+		}
+		list = append(list, &mesos_v1_master.Response_GetAgents_Agent{
+			AgentInfo: &mesos_v1.AgentInfo{
+				Id:       &mesos_v1.AgentID{Value: proto.String(id)},
+				Hostname: proto.String(host),
+				Port:     proto.Int32(int32(portNumber)),
+			},
+			Active:  proto.Bool(active),
+			Version: proto.String("1.11.0"),
+		})
+	}
+	output, err := proto.Marshal(&mesos_v1_master.Response{
+		Type:      mesos_v1_master.Response_GET_AGENTS.Enum(),
+		GetAgents: &mesos_v1_master.Response_GetAgents{Agents: list},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write(output)
+	}))
+}
+
+// newHealthyAgentServer returns an agent that reports itself healthy after
+// calling before.
+func newHealthyAgentServer(t *testing.T, before func()) *httptest.Server {
+	healthy := true
+	output, err := proto.Marshal(&mesos_v1_agent.Response{
+		Type:      mesos_v1_agent.Response_GET_HEALTH.Enum(),
+		GetHealth: &mesos_v1_agent.Response_GetHealth{Healthy: &healthy},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if before != nil {
+			before()
+		}
+		rw.Write(output)
+	}))
+}
+
+func getAgentHealth(ctx context.Context, agent AgentAPI) (*mesos_v1_agent.Response, error) {
+	return agent.GetHealth(ctx)
+}
+
+func newTestCluster(t *testing.T, masterURL string, b func(b *ClusterBuilder)) *Cluster {
+	m, err := NewMasterBuilder(masterURL).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := NewClusterBuilder(m)
+	if b != nil {
+		b(builder)
+	}
+	c, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClusterRunKeepsPartialFailures(t *testing.T) {
+	healthy := newHealthyAgentServer(t, nil)
+	defer healthy.Close()
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	masterServer := newAgentsServer(t, map[string]*httptest.Server{"a1": healthy, "a2": healthy, "a3": gone})
+	defer masterServer.Close()
+	c := newTestCluster(t, masterServer.URL, nil)
+
+	results, err := c.Run(context.Background(), nil, getAgentHealth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || len(results.Succeeded()) != 2 || len(results.Failed()) != 1 {
+		t.Fatalf("expected 2 of 3 agents to succeed, got %+v", results)
+	}
+	for _, result := range results.Succeeded() {
+		if !result.Response.GetGetHealth().GetHealthy() {
+			t.Errorf("expected %s to be healthy", result.Agent.GetAgentInfo().GetId().GetValue())
+		}
+	}
+
+	var clusterErr ClusterError
+	if !errors.As(results.Err(), &clusterErr) {
+		t.Fatalf("expected ClusterError, got %v", results.Err())
+	}
+	if clusterErr.Total != 3 || len(clusterErr.Errors) != 1 || clusterErr.Errors["a3"] == nil {
+		t.Errorf("unexpected ClusterError %v", clusterErr)
+	}
+}
+
+func TestClusterRunFilter(t *testing.T) {
+	healthy := newHealthyAgentServer(t, nil)
+	defer healthy.Close()
+
+	masterServer := newAgentsServer(t, map[string]*httptest.Server{"a1": healthy, "a2": healthy}, "a2")
+	defer masterServer.Close()
+	c := newTestCluster(t, masterServer.URL, nil)
+
+	results, err := c.Run(context.Background(), ActiveAgents, getAgentHealth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Agent.GetAgentInfo().GetId().GetValue() != "a1" {
+		t.Errorf("expected only a1, got %+v", results)
+	}
+	if results.Err() != nil {
+		t.Error(results.Err())
+	}
+}
+
+func TestClusterRunBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	slow := newHealthyAgentServer(t, func() {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	defer slow.Close()
+
+	agents := map[string]*httptest.Server{}
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5", "a6"} {
+		agents[id] = slow
+	}
+	masterServer := newAgentsServer(t, agents)
+	defer masterServer.Close()
+	c := newTestCluster(t, masterServer.URL, func(b *ClusterBuilder) { b.SetMaxConcurrency(2) })
+
+	results, err := c.Run(context.Background(), nil, getAgentHealth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Err() != nil {
+		t.Error(results.Err())
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max > 2 {
+		t.Errorf("expected at most 2 calls in flight, got %d", max)
+	}
+}
+
+func TestClusterRunAgentTimeout(t *testing.T) {
+	release := make(chan struct{})
+	stuck := newHealthyAgentServer(t, func() { <-release })
+	defer stuck.Close()
+	defer close(release)
+
+	masterServer := newAgentsServer(t, map[string]*httptest.Server{"a1": stuck})
+	defer masterServer.Close()
+	c := newTestCluster(t, masterServer.URL, func(b *ClusterBuilder) { b.SetAgentTimeout(50 * time.Millisecond) })
+
+	results, err := c.Run(context.Background(), nil, getAgentHealth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %+v", results)
+	}
+}
+
+func TestClusterReusesAgents(t *testing.T) {
+	masterServer := newAgentsServer(t, map[string]*httptest.Server{})
+	defer masterServer.Close()
+	configured := 0
+	c := newTestCluster(t, masterServer.URL, func(b *ClusterBuilder) {
+		b.SetConfigureAgent(func(b *AgentBuilder) { configured++ })
+	})
+
+	agentInfo := &mesos_v1.AgentInfo{Hostname: proto.String("10.0.0.1")}
+	first, err := c.Agent(agentInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Agent(agentInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || configured != 1 {
+		t.Errorf("expected the Agent to be built once, built %d times", configured)
+	}
+}
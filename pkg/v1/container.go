@@ -44,9 +44,9 @@ func (a *Agent) GetContainers(ctx context.Context) (response *mesos_v1_agent.Res
 	return
 }
 
-// LaunchContainer launches a nested container. Any authorized entity,
-// including the executor itself, its tasks, or the operator can use this API to
-// launch a nested container.
+// LaunchContainer launches a standalone container, one that is not part of a
+// task or executor. Operators use standalone containers to run jobs on the
+// agent, see StandaloneContainer.
 func (a *Agent) LaunchContainer(ctx context.Context, call *mesos_v1_agent.Call_LaunchContainer) (err error) {
 	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_LAUNCH_CONTAINER
 	var message proto.Message = &mesos_v1_agent.Call{Type: &callType, LaunchContainer: call}
@@ -56,6 +56,44 @@ func (a *Agent) LaunchContainer(ctx context.Context, call *mesos_v1_agent.Call_L
 	return
 }
 
+// WaitContainer waits for a standalone container, one launched with
+// LaunchContainer, to terminate or exit and returns its exit status.
+func (a *Agent) WaitContainer(ctx context.Context, call *mesos_v1_agent.Call_WaitContainer) (
+	response *mesos_v1_agent.Response, err error,
+) {
+	var httpResponse *http.Response
+	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_WAIT_CONTAINER
+	var message proto.Message = &mesos_v1_agent.Call{Type: &callType, WaitContainer: call}
+	response = &mesos_v1_agent.Response{}
+	httpResponse, err = a.client.makeCall(ctx, message, response)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// KillContainer sends a signal to a standalone container, one launched with
+// LaunchContainer. If Signal is not set, the container is sent SIGKILL and
+// destroyed.
+func (a *Agent) KillContainer(ctx context.Context, call *mesos_v1_agent.Call_KillContainer) (err error) {
+	var httpResponse *http.Response
+	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_KILL_CONTAINER
+	var message proto.Message = &mesos_v1_agent.Call{Type: &callType, KillContainer: call}
+	httpResponse, err = a.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// RemoveContainer removes the runtime directory and metadata of a standalone
+// container, one launched with LaunchContainer. The container must have
+// terminated.
+func (a *Agent) RemoveContainer(ctx context.Context, call *mesos_v1_agent.Call_RemoveContainer) (err error) {
+	var httpResponse *http.Response
+	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_REMOVE_CONTAINER
+	var message proto.Message = &mesos_v1_agent.Call{Type: &callType, RemoveContainer: call}
+	httpResponse, err = a.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
 // LaunchNestedContainer launches a nested container. Any authorized entity,
 // including the executor itself, its tasks, or the operator can use this API to
 // launch a nested container.
@@ -105,29 +143,22 @@ func (a *Agent) LaunchNestedContainerSession(
 ) (err error) {
 	var httpResponse *http.Response
 	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_LAUNCH_NESTED_CONTAINER_SESSION
-	var callMsg proto.Message = &mesos_v1_agent.Call{Type: &callType, LaunchNestedContainerSession: call}
-
-	httpResponse, err = a.client.makeCall(ctx, callMsg, nil)
+	httpResponse, err = a.launchNestedContainerSession(ctx, call)
 	if err != nil {
 		return
 	}
 	var reader *bufio.Reader = bufio.NewReader(httpResponse.Body)
 	defer httpResponse.Body.Close()
+	defer a.client.metrics.openStream(callType.String(), a.client.target)()
 	for {
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 			return
 		default:
-			var msg []byte
-			msg, err = readRecordioMessage(reader)
-			if err != nil {
-				return
-			}
-
-			// Unmarshal data into a mesos_v1_master.Event
+			// Unmarshal data into a mesos_v1_agent.ProcessIO
 			processIO := &mesos_v1_agent.ProcessIO{}
-			err = proto.Unmarshal(msg, processIO)
+			err = a.client.readRecord(reader, callType, processIO)
 			if err != nil {
 				return
 			}
@@ -136,6 +167,17 @@ func (a *Agent) LaunchNestedContainerSession(
 	}
 }
 
+// launchNestedContainerSession sends the LAUNCH_NESTED_CONTAINER_SESSION call
+// and returns the response, whose body streams the output of the container.
+func (a *Agent) launchNestedContainerSession(
+	ctx context.Context, call *mesos_v1_agent.Call_LaunchNestedContainerSession,
+) (httpResponse *http.Response, err error) {
+	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_LAUNCH_NESTED_CONTAINER_SESSION
+	var callMsg proto.Message = &mesos_v1_agent.Call{Type: &callType, LaunchNestedContainerSession: call}
+	httpResponse, err = a.client.makeCall(withStreamingResponse(ctx), callMsg, nil)
+	return
+}
+
 // This call attaches to the STDIN of the primary process of a container and
 // streams input to it. This call can only be made against containers that have
 // been launched with an associated IOSwitchboard (i.e. nested containers
@@ -165,21 +207,16 @@ func (a *Agent) AttachContainerInput(
 	}
 	var reader *bufio.Reader = bufio.NewReader(httpResponse.Body)
 	defer httpResponse.Body.Close()
+	defer a.client.metrics.openStream(callType.String(), a.client.target)()
 	for {
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 			return
 		default:
-			var msg []byte
-			msg, err = readRecordioMessage(reader)
-			if err != nil {
-				return
-			}
-
-			// Unmarshal data into a mesos_v1_master.Event
+			// Unmarshal data into a mesos_v1_agent.ProcessIO
 			processIO := &mesos_v1_agent.ProcessIO{}
-			err = proto.Unmarshal(msg, processIO)
+			err = a.client.readRecord(reader, callType, processIO)
 			if err != nil {
 				return
 			}
@@ -203,27 +240,22 @@ func (a *Agent) AttachContainerOutput(
 	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_ATTACH_CONTAINER_OUTPUT
 	var callMsg proto.Message = &mesos_v1_agent.Call{Type: &callType, AttachContainerOutput: call}
 
-	httpResponse, err = a.client.makeCall(ctx, callMsg, nil)
+	httpResponse, err = a.client.makeCall(withStreamingResponse(ctx), callMsg, nil)
 	if err != nil {
 		return
 	}
 	var reader *bufio.Reader = bufio.NewReader(httpResponse.Body)
 	defer httpResponse.Body.Close()
+	defer a.client.metrics.openStream(callType.String(), a.client.target)()
 	for {
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 			return
 		default:
-			var msg []byte
-			msg, err = readRecordioMessage(reader)
-			if err != nil {
-				return
-			}
-
-			// Unmarshal data into a mesos_v1_master.Event
+			// Unmarshal data into a mesos_v1_agent.ProcessIO
 			processIO := &mesos_v1_agent.ProcessIO{}
-			err = proto.Unmarshal(msg, processIO)
+			err = a.client.readRecord(reader, callType, processIO)
 			if err != nil {
 				return
 			}
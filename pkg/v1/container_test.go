@@ -397,7 +397,7 @@ func TestAttachContainerOutput(t *testing.T) {
 	}
 }
 
-func TestRemoveContainer(t *testing.T) {
+func TestRemoveNestedContainer(t *testing.T) {
 	s := NewTestProtobufServer(AgentClient)
 	defer s.Teardown()
 
@@ -413,3 +413,74 @@ func TestRemoveContainer(t *testing.T) {
 		t.Error("expected nil, got %s", err)
 	}
 }
+
+func TestWaitContainer(t *testing.T) {
+	s := NewTestProtobufServer(AgentClient)
+	defer s.Teardown()
+
+	// Request
+	containerIDValue := "test-id"
+	call := &mesos_v1_agent.Call_WaitContainer{
+		ContainerId: &mesos_v1.ContainerID{Value: &containerIDValue},
+	}
+
+	// Response
+	responseType := mesos_v1_agent.Response_WAIT_CONTAINER
+	exitStatus := int32(256)
+	response := &mesos_v1_agent.Response{
+		Type: &responseType,
+		WaitContainer: &mesos_v1_agent.Response_WaitContainer{
+			ExitStatus: &exitStatus,
+		},
+	}
+
+	// Marshal to byte string
+	output, err := proto.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetOutput(output).Handle()
+
+	data, err := s.Agent().WaitContainer(s.Ctx(), call)
+	if err != nil {
+		t.Fatalf("expected nil, got %s", err)
+	}
+	if data.GetWaitContainer().GetExitStatus() != int32(256) {
+		t.Errorf("expected 256, got %d", data.GetWaitContainer().GetExitStatus())
+	}
+}
+
+func TestKillContainer(t *testing.T) {
+	s := NewTestProtobufServer(AgentClient)
+	defer s.Teardown()
+
+	containerIDValue := "test-id"
+	signal := int32(15)
+	call := &mesos_v1_agent.Call_KillContainer{
+		ContainerId: &mesos_v1.ContainerID{Value: &containerIDValue},
+		Signal:      &signal,
+	}
+	s.SetOutput(make([]byte, 0)).Handle()
+	err := s.Agent().KillContainer(s.Ctx(), call)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+	}
+}
+
+func TestRemoveContainer(t *testing.T) {
+	s := NewTestProtobufServer(AgentClient)
+	defer s.Teardown()
+
+	containerIDValue := "test-id"
+	call := &mesos_v1_agent.Call_RemoveContainer{
+		ContainerId: &mesos_v1.ContainerID{Value: &containerIDValue},
+	}
+
+	s.SetOutput(make([]byte, 0)).Handle()
+
+	err := s.Agent().RemoveContainer(s.Ctx(), call)
+	if err != nil {
+		t.Errorf("expected nil, got %s", err)
+	}
+}
@@ -66,6 +66,75 @@ For example:
     log.Fatal(err)
   }
 
+When the masters run as an HA set, give the MasterBuilder the URL of every
+master. The Master follows the redirect sent by a non-leading master, caches
+the leading master and looks for a new leader when the cached one can no
+longer be reached.
+
+For example:
+
+  masterClient, err = v1.NewMasterBuilder(
+    "http://10.0.0.1:5050",
+    "http://10.0.0.2:5050",
+    "http://10.0.0.3:5050",
+  ).Build()
+
+When the masters or agents require HTTP authentication, configure either
+Basic credentials or a CredentialProvider that supplies bearer tokens. A
+request rejected with a 401 is sent once more after the provider refreshes its
+token.
+
+For example:
+
+  masterClient, err = v1.NewMasterBuilder("http://127.0.0.1:5050").
+    SetBasicAuth("principal", "secret").
+    Build()
+
+  agentClient, err = v1.NewAgentBuilder("http://127.0.0.1:5051").
+    SetCredentialProvider(myProvider).
+    Build()
+
+For HTTPS endpoints signed by an internal CA, give the builders the CA bundle
+and, for mutual TLS, a client certificate and key. The files are reloaded when
+they are rotated on disk. Agents built with Master.NewAgentBuilder inherit the
+TLS settings of the Master.
+
+For example:
+
+  masterClient, err = v1.NewMasterBuilder("https://10.0.0.1:5050").
+    SetCACertFile("/etc/mesos/ca.pem").
+    SetClientCertFiles("/etc/mesos/client.pem", "/etc/mesos/client-key.pem").
+    Build()
+
+  agentClient, err = masterClient.NewAgentBuilder(agentInfo).Build()
+
+Calls, responses and streamed records are encoded as protobuf by default. Use
+SetEncoding(v1.EncodingJSON) on either builder to exchange JSON instead, which
+is easier to inspect through proxies.
+
+Calls can be traced with OpenTelemetry by giving either builder a
+trace.TracerProvider. Each call produces a span named after its Call_Type and
+the trace context is propagated through the request headers.
+
+For example:
+
+  masterClient, err = v1.NewMasterBuilder("http://127.0.0.1:5050").
+    SetTracerProvider(otel.GetTracerProvider()).
+    Build()
+
+GetMetricsSnapshot returns the metrics of a master or agent as a
+MetricsSnapshot, with typed getters for the well-known metrics, glob lookups
+and a Diff that turns two snapshots into rates.
+
+For example:
+
+  previous, err := masterClient.GetMetricsSnapshot(ctx)
+  time.Sleep(10 * time.Second)
+  current, err := masterClient.GetMetricsSnapshot(ctx)
+  diff, err := current.Diff(previous)
+  rate, _ := diff.Rate("master/messages_received")
+  fmt.Printf("elected: %t, messages received per second: %f", current.MasterElected(), rate)
+
 With clients configured, you can now interact with the API.
 
 For example:
@@ -118,6 +187,19 @@ For example:
 For the most part, you should not have to worry about HTTP when using this
 client. However, if a request fails with a response code outside of the 200
 range, the calling method will return an HTTPError. This error holds the
-response code and message, including both in the error message.
+response code and body, the type of the call and the number of attempts made.
+When the server cannot be reached after one or more retries, a
+RetriesExhaustedError is returned instead, and a malformed record on a streamed response is returned
+as a StreamDecodeError. Use errors.Is to branch on the cause.
+
+For example:
+
+  _, err = masterClient.GetHealth(ctx)
+  switch {
+  case errors.Is(err, v1.ErrUnauthorized), errors.Is(err, v1.ErrForbidden):
+    log.Fatal("check the configured credentials: ", err)
+  case errors.Is(err, v1.ErrNotLeader), errors.Is(err, v1.ErrRetriesExhausted):
+    // try again later
+  }
 */
 package v1
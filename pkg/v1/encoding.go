@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"bytes"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+)
+
+// Encoding is the wire encoding of the calls, responses and streamed records
+// exchanged with the Mesos Operator API.
+type Encoding int
+
+const (
+	// EncodingProtobuf encodes messages as binary protobuf. This is the
+	// default.
+	EncodingProtobuf Encoding = iota
+	// EncodingJSON encodes messages as JSON, which is easier to read through
+	// proxies and to capture in bug reports.
+	EncodingJSON
+)
+
+// String implements the fmt.Stringer interface for Encoding.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingProtobuf:
+		return "protobuf"
+	case EncodingJSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
+
+// contentType returns the media type of the encoding, used for both the
+// Content-Type and Accept headers.
+func (e Encoding) contentType() string {
+	if e == EncodingJSON {
+		return "application/json"
+	}
+	return "application/x-protobuf"
+}
+
+// marshal encodes pb.
+func (e Encoding) marshal(pb proto.Message) (b []byte, err error) {
+	if e == EncodingJSON {
+		var buf bytes.Buffer
+		var m *jsonpb.Marshaler = &jsonpb.Marshaler{}
+		err = m.Marshal(&buf, pb)
+		b = buf.Bytes()
+		return
+	}
+	b, err = proto.Marshal(pb)
+	return
+}
+
+// unmarshal decodes b into pb. Unknown JSON fields are ignored, as are unknown
+// protobuf fields, so that responses from newer versions of Mesos still
+// decode.
+func (e Encoding) unmarshal(b []byte, pb proto.Message) (err error) {
+	if e == EncodingJSON {
+		var u *jsonpb.Unmarshaler = &jsonpb.Unmarshaler{AllowUnknownFields: true}
+		err = u.Unmarshal(bytes.NewReader(b), pb)
+		return
+	}
+	err = proto.Unmarshal(b, pb)
+	return
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newJSONServer returns a server that expects JSON encoded calls. It records
+// the type of each call in calls and answers with handle.
+func newJSONServer(t *testing.T, calls chan<- string, handle func(rw http.ResponseWriter)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected application/json, got %s", req.Header.Get("Content-Type"))
+		}
+		// Streamed responses are requested as recordio of JSON records
+		accept := req.Header.Get("Accept")
+		if accept == "application/recordio" {
+			accept = req.Header.Get("Message-Accept")
+		}
+		if accept != "application/json" {
+			t.Errorf("expected application/json, got %s", accept)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var call struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(body, &call); err != nil {
+			t.Error(err)
+		}
+		calls <- call.Type
+		handle(rw)
+	}))
+}
+
+// writeJSONRecords writes each message as a JSON record of a recordio stream.
+func writeJSONRecords(t *testing.T, rw http.ResponseWriter, messages ...proto.Message) {
+	for _, message := range messages {
+		s, err := (&jsonpb.Marshaler{}).MarshalToString(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(rw, "%d\n%s", len(s), s)
+	}
+	rw.(http.Flusher).Flush()
+}
+
+func TestMasterJSONEncoding(t *testing.T) {
+	calls := make(chan string, 1)
+	server := newJSONServer(t, calls, func(rw http.ResponseWriter) {
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(rw, `{"type": "GET_HEALTH", "get_health": {"healthy": true}, "unknown_field": 1}`)
+	})
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetEncoding(EncodingJSON).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if callType := <-calls; callType != "GET_HEALTH" {
+		t.Errorf("expected GET_HEALTH, got %s", callType)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+}
+
+func TestMasterSubscribeJSONEncoding(t *testing.T) {
+	calls := make(chan string, 1)
+	server := newJSONServer(t, calls, func(rw http.ResponseWriter) {
+		writeJSONRecords(t, rw, subscribedEvent(), agentRemovedEvent("agent-1"))
+	})
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetEncoding(EncodingJSON).SetMaxMissedHeartbeats(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := make(EventStream, 2)
+	m.Subscribe(context.Background(), es)
+	if callType := <-calls; callType != "SUBSCRIBE" {
+		t.Errorf("expected SUBSCRIBE, got %s", callType)
+	}
+	if len(es) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(es))
+	}
+	if event := <-es; event.GetType() != mesos_v1_master.Event_SUBSCRIBED {
+		t.Errorf("expected SUBSCRIBED, got %s", event.GetType())
+	}
+	if event := <-es; event.GetAgentRemoved().GetAgentId().GetValue() != "agent-1" {
+		t.Errorf("expected agent-1, got %s", event.GetAgentRemoved().GetAgentId().GetValue())
+	}
+}
+
+func TestAgentAttachContainerOutputJSONEncoding(t *testing.T) {
+	stdoutType := mesos_v1_agent.ProcessIO_Data_STDOUT
+	processIOType := mesos_v1_agent.ProcessIO_DATA
+	processIO := &mesos_v1_agent.ProcessIO{
+		Type: &processIOType,
+		Data: &mesos_v1_agent.ProcessIO_Data{Type: &stdoutType, Data: []byte("stdout")},
+	}
+	calls := make(chan string, 1)
+	server := newJSONServer(t, calls, func(rw http.ResponseWriter) {
+		writeJSONRecords(t, rw, processIO)
+	})
+	defer server.Close()
+
+	a, err := NewAgentBuilder(server.URL).SetEncoding(EncodingJSON).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	containerIDValue := "test-id"
+	call := &mesos_v1_agent.Call_AttachContainerOutput{
+		ContainerId: &mesos_v1.ContainerID{Value: &containerIDValue},
+	}
+	processIOStream := make(ProcessIOStream, 1)
+	a.AttachContainerOutput(context.Background(), call, processIOStream)
+	if callType := <-calls; callType != "ATTACH_CONTAINER_OUTPUT" {
+		t.Errorf("expected ATTACH_CONTAINER_OUTPUT, got %s", callType)
+	}
+	if len(processIOStream) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(processIOStream))
+	}
+	if data := (<-processIOStream).GetData().GetData(); string(data) != "stdout" {
+		t.Errorf("expected stdout, got %s", data)
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	_, err := NewAgentBuilder("http://127.0.0.1:5051").SetEncoding(Encoding(42)).Build()
+	if err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
@@ -0,0 +1,174 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Sentinel errors that the errors returned by the Master and Agent can be
+// compared to with errors.Is.
+//
+// e.g.
+//
+// 	_, err = m.GetHealth(ctx)
+// 	if errors.Is(err, ErrUnauthorized) {
+// 		// refresh credentials
+// 	}
+var (
+	// ErrNotLeader is reported when the master is not the leading master and
+	// could not redirect to it, such as while no leader is elected.
+	ErrNotLeader = errors.New("not the leading master")
+	// ErrBadRequest is reported for a response with status 400.
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized is reported for a response with status 401.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is reported for a response with status 403.
+	ErrForbidden = errors.New("forbidden")
+	// ErrServiceUnavailable is reported for a response with status 503.
+	ErrServiceUnavailable = errors.New("service unavailable")
+	// ErrRetriesExhausted is reported by a RetriesExhaustedError.
+	ErrRetriesExhausted = errors.New("retries exhausted")
+	// ErrStreamDecode is reported by a StreamDecodeError.
+	ErrStreamDecode = errors.New("stream decode error")
+)
+
+// HTTPError is a custom error type for HTTP errors outside of the 200 range.
+// Compare it to ErrNotLeader, ErrBadRequest, ErrUnauthorized, ErrForbidden and
+// ErrServiceUnavailable with errors.Is, or use errors.As to inspect it.
+type HTTPError struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Body is the body of the response, which usually explains the failure.
+	Body string
+	// CallType is the type of the call that failed, e.g. GET_HEALTH.
+	CallType string
+	// Attempts is the number of times the call was sent.
+	Attempts int
+}
+
+// Error implements the error interface for HTTPError, printing call_type,
+// status_code and msg information.
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("request failed: call_type: %s status_code: %d msg: %s", e.CallType, e.StatusCode, e.Body)
+}
+
+// Is reports whether the HTTPError matches the sentinel error target.
+func (e HTTPError) Is(target error) bool {
+	switch target {
+	case ErrNotLeader:
+		// A non-leading master redirects to the leader. Without a leader, it
+		// responds with 503 instead.
+		return e.StatusCode == http.StatusTemporaryRedirect ||
+			e.StatusCode == http.StatusServiceUnavailable && strings.Contains(e.Body, "No leader elected")
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrServiceUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// RetriesExhaustedError is returned when every attempt of a call that was
+// retried failed without a response, such as when the server could not be
+// reached. Err holds the error of the last attempt. A call that failed on its
+// only attempt returns the error of that attempt instead.
+type RetriesExhaustedError struct {
+	// CallType is the type of the call that failed, e.g. GET_HEALTH.
+	CallType string
+	// Attempts is the number of times the call was sent.
+	Attempts int
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// Error implements the error interface for RetriesExhaustedError.
+func (e RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("%s exceeded %d retries: %s", e.CallType, e.Attempts-1, e.Err)
+}
+
+// Is reports whether target is ErrRetriesExhausted.
+func (e RetriesExhaustedError) Is(target error) bool {
+	return target == ErrRetriesExhausted
+}
+
+// Unwrap returns the error of the last attempt.
+func (e RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// StreamDecodeError is returned when a record of a streamed response, such as
+// the events of Subscribe or the output of AttachContainerOutput, could not be
+// decoded.
+type StreamDecodeError struct {
+	// CallType is the type of the call whose stream failed, e.g. SUBSCRIBE.
+	CallType string
+	// Err is the error that occurred while decoding.
+	Err error
+}
+
+// Error implements the error interface for StreamDecodeError.
+func (e StreamDecodeError) Error() string {
+	return fmt.Sprintf("%s stream decode error: %s", e.CallType, e.Err)
+}
+
+// Is reports whether target is ErrStreamDecode.
+func (e StreamDecodeError) Is(target error) bool {
+	return target == ErrStreamDecode
+}
+
+// Unwrap returns the error that occurred while decoding.
+func (e StreamDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ClusterError is returned by AgentResults.Err when a call run by a Cluster
+// failed on some of the agents.
+type ClusterError struct {
+	// Errors holds the error of each agent the call failed on, keyed by agent
+	// ID.
+	Errors map[string]error
+	// Total is the number of agents the call was run on.
+	Total int
+}
+
+// Error implements the error interface for ClusterError.
+func (e ClusterError) Error() string {
+	var ids []string = make([]string, 0, len(e.Errors))
+	for id := range e.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var msgs []string = make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", id, e.Errors[id]))
+	}
+	return fmt.Sprintf("call failed on %d of %d agents: %s", len(e.Errors), e.Total, strings.Join(msgs, "; "))
+}
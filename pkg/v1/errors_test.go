@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func TestHTTPErrorIs(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		body       string
+		target     error
+	}{
+		{http.StatusTemporaryRedirect, "", ErrNotLeader},
+		{http.StatusServiceUnavailable, "No leader elected", ErrNotLeader},
+		{http.StatusServiceUnavailable, "No leader elected", ErrServiceUnavailable},
+		{http.StatusServiceUnavailable, "", ErrServiceUnavailable},
+		{http.StatusBadRequest, "", ErrBadRequest},
+		{http.StatusUnauthorized, "", ErrUnauthorized},
+		{http.StatusForbidden, "", ErrForbidden},
+	}
+	for _, test := range tests {
+		s := NewTestProtobufServer(MasterClient)
+		s.mux.HandleFunc("/api/v1", func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(test.statusCode)
+			fmt.Fprint(rw, test.body)
+		})
+		_, _, err := s.Master().sendSimpleCall(s.Ctx(), mesos_v1_master.Call_GET_HEALTH)
+		s.Teardown()
+		if !errors.Is(err, test.target) {
+			t.Errorf("expected %d %q to be %v, got %v", test.statusCode, test.body, test.target, err)
+		}
+		if test.statusCode != http.StatusServiceUnavailable && errors.Is(err, ErrServiceUnavailable) {
+			t.Errorf("expected %d not to be %v", test.statusCode, ErrServiceUnavailable)
+		}
+		var httpErr HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("expected an HTTPError, got %T", err)
+		}
+		if httpErr.StatusCode != test.statusCode {
+			t.Errorf("expected %d, got %d", test.statusCode, httpErr.StatusCode)
+		}
+		if httpErr.Body != test.body {
+			t.Errorf("expected %q, got %q", test.body, httpErr.Body)
+		}
+		if httpErr.CallType != "GET_HEALTH" {
+			t.Errorf("expected GET_HEALTH, got %s", httpErr.CallType)
+		}
+		if httpErr.Attempts != 1 {
+			t.Errorf("expected 1 attempt, got %d", httpErr.Attempts)
+		}
+	}
+}
+
+func TestRetriesExhaustedError(t *testing.T) {
+	// Nothing listens on a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetMaxRetries(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = m.sendSimpleCall(context.Background(), mesos_v1_master.Call_GET_STATE)
+	if !errors.Is(err, ErrRetriesExhausted) {
+		t.Fatalf("expected %v, got %v", ErrRetriesExhausted, err)
+	}
+	var retriesErr RetriesExhaustedError
+	if !errors.As(err, &retriesErr) {
+		t.Fatalf("expected a RetriesExhaustedError, got %T", err)
+	}
+	if retriesErr.CallType != "GET_STATE" {
+		t.Errorf("expected GET_STATE, got %s", retriesErr.CallType)
+	}
+	if retriesErr.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", retriesErr.Attempts)
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Errorf("expected the last attempt's *url.Error, got %v", retriesErr.Err)
+	}
+}
+
+func TestStreamDecodeError(t *testing.T) {
+	for _, record := range []string{"5\nnot a", "size\n"} {
+		s := NewTestProtobufServer(MasterClient)
+		s.SetOutput([]byte(record)).Handle()
+		m, err := NewMasterBuilder(s.httpServer.URL).SetHTTPClient(s.httpClient).SetMaxMissedHeartbeats(0).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Subscribe(s.Ctx(), make(EventStream, 1))
+		s.Teardown()
+		if !errors.Is(err, ErrStreamDecode) {
+			t.Errorf("expected %v for %q, got %v", ErrStreamDecode, record, err)
+		}
+		var decodeErr StreamDecodeError
+		if errors.As(err, &decodeErr) && decodeErr.CallType != "SUBSCRIBE" {
+			t.Errorf("expected SUBSCRIBE, got %s", decodeErr.CallType)
+		}
+	}
+}
+
+func TestUnreachableServerReturnsError(t *testing.T) {
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	m, err := NewMasterBuilder(gone.URL).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The response is closed by GetHealth, which must not panic
+	if _, err = m.GetHealth(context.Background()); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
@@ -0,0 +1,540 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+)
+
+// WindowSize is the size of a terminal in characters.
+type WindowSize struct {
+	Rows    uint32
+	Columns uint32
+}
+
+// Exec runs a command in a new nested container of a running container and
+// connects it to local readers and writers, the way kubectl exec does. Build
+// an Exec with the ExecBuilder returned by Agent.NewExecBuilder.
+type Exec struct {
+	agent             *Agent
+	parentID          *mesos_v1.ContainerID
+	command           *mesos_v1.CommandInfo
+	stdin             io.Reader
+	stdout            io.Writer
+	stderr            io.Writer
+	tty               bool
+	windowSize        *WindowSize
+	resize            <-chan WindowSize
+	heartbeatInterval time.Duration
+	cleanupTimeout    time.Duration
+}
+
+// ContainerResult is the outcome of a container, as reported by
+// WAIT_NESTED_CONTAINER for an Exec or by WAIT_CONTAINER for a
+// StandaloneContainer.
+type ContainerResult struct {
+	// ContainerID is the ID of the container the command ran in.
+	ContainerID *mesos_v1.ContainerID
+	// State is the state of the container as reported by the agent, e.g.
+	// TASK_FINISHED.
+	State mesos_v1.TaskState
+	// Message explains why the container terminated, if known.
+	Message    string
+	exitStatus *int32
+}
+
+// ExitStatus returns the wait status of the command, as returned by waitpid,
+// and whether the agent reported one.
+func (r ContainerResult) ExitStatus() (status int32, ok bool) {
+	if r.exitStatus == nil {
+		return
+	}
+	status, ok = *r.exitStatus, true
+	return
+}
+
+// ExitCode returns the exit code of the command. A command terminated by a
+// signal reports 128 plus the signal number, as shells do. ExitCode returns
+// -1 if the agent did not report a wait status.
+func (r ContainerResult) ExitCode() int {
+	var status int32
+	var ok bool
+	status, ok = r.ExitStatus()
+	if !ok {
+		return -1
+	}
+	if status&0x7f == 0 {
+		return int(status>>8) & 0xff
+	}
+	return 128 + int(status&0x7f)
+}
+
+// ExecBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct.
+type ExecBuilder struct {
+	exec *Exec
+}
+
+// NewExecBuilder returns a pointer to an ExecBuilder that runs command, the
+// path of an executable followed by its arguments, in a container nested in
+// the container with the ID parentID.
+//
+// e.g.
+//
+// 	var e *Exec
+// 	e, err = a.NewExecBuilder(parentID, "/bin/sh", "-c", "ls /").SetStdout(os.Stdout).Build()
+// 	var result ContainerResult
+// 	result, err = e.Run(ctx)
+// 	os.Exit(result.ExitCode())
+func (a *Agent) NewExecBuilder(parentID *mesos_v1.ContainerID, command ...string) *ExecBuilder {
+	var shell bool = false
+	var commandInfo *mesos_v1.CommandInfo = &mesos_v1.CommandInfo{Shell: &shell, Arguments: command}
+	if len(command) > 0 {
+		commandInfo.Value = &command[0]
+	}
+	return &ExecBuilder{
+		exec: &Exec{
+			agent:             a,
+			parentID:          parentID,
+			command:           commandInfo,
+			heartbeatInterval: 30 * time.Second,
+			cleanupTimeout:    30 * time.Second,
+		},
+	}
+}
+
+// SetCommandInfo sets the CommandInfo of the command, replacing the command
+// given to NewExecBuilder, and returns a pointer to the ExecBuilder. Use it to
+// run a shell command or to set the user or environment.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID).SetCommandInfo(&mesos_v1.CommandInfo{Value: &script})
+func (b *ExecBuilder) SetCommandInfo(command *mesos_v1.CommandInfo) *ExecBuilder {
+	b.exec.command = command
+	return b
+}
+
+// SetStdin sets the reader whose bytes are sent to the standard input of the
+// command and returns a pointer to the ExecBuilder. The end of the reader
+// closes the standard input. If SetStdin is not called, the command has no
+// standard input.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/cat").SetStdin(os.Stdin)
+func (b *ExecBuilder) SetStdin(stdin io.Reader) *ExecBuilder {
+	b.exec.stdin = stdin
+	return b
+}
+
+// SetStdout sets the writer the standard output of the command is written to
+// and returns a pointer to the ExecBuilder. If SetStdout is not called, the
+// output is discarded.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/ls").SetStdout(os.Stdout)
+func (b *ExecBuilder) SetStdout(stdout io.Writer) *ExecBuilder {
+	b.exec.stdout = stdout
+	return b
+}
+
+// SetStderr sets the writer the standard error of the command is written to
+// and returns a pointer to the ExecBuilder. If SetStderr is not called, the
+// output is discarded.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/ls").SetStderr(os.Stderr)
+func (b *ExecBuilder) SetStderr(stderr io.Writer) *ExecBuilder {
+	b.exec.stderr = stderr
+	return b
+}
+
+// SetTTY sets whether the command runs in a pseudo terminal and returns a
+// pointer to the ExecBuilder. With a terminal, all output is written to the
+// standard output writer. If SetTTY is not called, no terminal is allocated.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/bash").SetTTY(true)
+func (b *ExecBuilder) SetTTY(tty bool) *ExecBuilder {
+	b.exec.tty = tty
+	return b
+}
+
+// SetWindowSize sets the initial size of the terminal and returns a pointer to
+// the ExecBuilder. It has no effect unless SetTTY is called.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/bash").SetTTY(true).SetWindowSize(WindowSize{Rows: 40, Columns: 120})
+func (b *ExecBuilder) SetWindowSize(windowSize WindowSize) *ExecBuilder {
+	b.exec.windowSize = &windowSize
+	return b
+}
+
+// SetResize sets a channel of new terminal sizes, such as the sizes read on
+// SIGWINCH, and returns a pointer to the ExecBuilder. Each size is sent to the
+// container as it arrives. It has no effect unless SetTTY is called.
+//
+// e.g.
+//
+// 	var resize chan WindowSize = make(chan WindowSize)
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/bash").SetTTY(true).SetResize(resize)
+func (b *ExecBuilder) SetResize(resize <-chan WindowSize) *ExecBuilder {
+	b.exec.resize = resize
+	return b
+}
+
+// SetHeartbeatInterval sets how often a heartbeat is sent over the standard
+// input connection to keep it open through idle timeouts and returns a
+// pointer to the ExecBuilder. If SetHeartbeatInterval is not called, it will
+// be set to 30 seconds.
+//
+// e.g.
+//
+// 	var b *ExecBuilder = a.NewExecBuilder(parentID, "/bin/bash").SetHeartbeatInterval(10 * time.Second)
+func (b *ExecBuilder) SetHeartbeatInterval(heartbeatInterval time.Duration) *ExecBuilder {
+	b.exec.heartbeatInterval = heartbeatInterval
+	return b
+}
+
+// Build returns a pointer to a constructed Exec.
+func (b *ExecBuilder) Build() (e *Exec, err error) {
+	switch {
+	case b.exec.parentID.GetValue() == "":
+		err = errors.New("parentID must not be empty")
+	case b.exec.command.GetValue() == "":
+		err = errors.New("command must not be empty")
+	case b.exec.heartbeatInterval <= 0:
+		err = errors.New("heartbeatInterval must be greater than 0")
+	}
+	if err != nil {
+		return
+	}
+	e = b.exec
+	return
+}
+
+// Run launches the command and copies its input and output until it exits.
+// It then returns the result reported by WAIT_NESTED_CONTAINER and removes the
+// nested container. When ctx is done, the connection to the container is
+// closed, which makes the agent destroy it.
+func (e *Exec) Run(ctx context.Context) (result ContainerResult, err error) {
+	var containerID *mesos_v1.ContainerID
+	containerID, err = newContainerID(e.parentID)
+	if err != nil {
+		return
+	}
+	result.ContainerID = containerID
+
+	var call *mesos_v1_agent.Call_LaunchNestedContainerSession = &mesos_v1_agent.Call_LaunchNestedContainerSession{
+		ContainerId: containerID,
+		Command:     e.command,
+	}
+	if e.tty {
+		var containerType mesos_v1.ContainerInfo_Type = mesos_v1.ContainerInfo_MESOS
+		call.Container = &mesos_v1.ContainerInfo{Type: &containerType, TtyInfo: &mesos_v1.TTYInfo{}}
+		if e.windowSize != nil {
+			call.Container.TtyInfo.WindowSize = &mesos_v1.TTYInfo_WindowSize{
+				Rows: &e.windowSize.Rows, Columns: &e.windowSize.Columns,
+			}
+		}
+	}
+
+	var sessionCtx context.Context
+	var cancel context.CancelFunc
+	sessionCtx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	var httpResponse *http.Response
+	httpResponse, err = e.agent.launchNestedContainerSession(sessionCtx, call)
+	if err != nil {
+		return
+	}
+	defer e.remove(containerID)
+
+	// The container exists once the session is established, so its input can
+	// be attached.
+	var inputErr chan error = make(chan error, 1)
+	if e.stdin != nil || e.tty {
+		go func() {
+			inputErr <- e.attachInput(sessionCtx, containerID)
+		}()
+	}
+
+	var outputErr chan error = make(chan error, 1)
+	go func() {
+		outputErr <- e.copyOutput(httpResponse.Body)
+	}()
+	select {
+	case err = <-outputErr:
+	case err = <-inputErr:
+		if err != nil {
+			// The input failed before the command exited, e.g. because the
+			// agent rejected it, so the session is ended rather than left
+			// waiting for input that will never come.
+			cancel()
+			<-outputErr
+			httpResponse.Body.Close()
+			return
+		}
+		err = <-outputErr
+	}
+	httpResponse.Body.Close()
+	if err != nil {
+		return
+	}
+	// The command has exited, so input that was not sent yet is dropped.
+	cancel()
+	select {
+	case err = <-inputErr:
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+	default:
+	}
+
+	var response *mesos_v1_agent.Response
+	response, err = e.agent.WaitNestedContainer(ctx, &mesos_v1_agent.Call_WaitNestedContainer{ContainerId: containerID})
+	if err != nil {
+		return
+	}
+	var wait *mesos_v1_agent.Response_WaitNestedContainer = response.GetWaitNestedContainer()
+	result.State = wait.GetState()
+	result.Message = wait.GetMessage()
+	result.exitStatus = wait.ExitStatus
+	return
+}
+
+// copyOutput writes the output streamed by the session to stdout and stderr
+// until the command exits.
+func (e *Exec) copyOutput(body io.Reader) (err error) {
+	var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_LAUNCH_NESTED_CONTAINER_SESSION
+	var reader *bufio.Reader = bufio.NewReader(body)
+	var stdout, stderr io.Writer = e.stdout, e.stderr
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+	for {
+		var processIO *mesos_v1_agent.ProcessIO = &mesos_v1_agent.ProcessIO{}
+		err = e.agent.client.readRecord(reader, callType, processIO)
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		if processIO.GetType() != mesos_v1_agent.ProcessIO_DATA {
+			continue
+		}
+		switch processIO.GetData().GetType() {
+		case mesos_v1_agent.ProcessIO_Data_STDOUT:
+			_, err = stdout.Write(processIO.GetData().GetData())
+		case mesos_v1_agent.ProcessIO_Data_STDERR:
+			_, err = stderr.Write(processIO.GetData().GetData())
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// attachInput streams the standard input, heartbeats and terminal sizes to
+// the container with an ATTACH_CONTAINER_INPUT call.
+func (e *Exec) attachInput(ctx context.Context, containerID *mesos_v1.ContainerID) (err error) {
+	var reader *io.PipeReader
+	var writer *io.PipeWriter
+	reader, writer = io.Pipe()
+	go func() {
+		writer.CloseWithError(e.writeInput(ctx, writer, containerID))
+	}()
+	var httpResponse *http.Response
+	httpResponse, err = e.agent.client.streamCall(
+		ctx, mesos_v1_agent.Call_ATTACH_CONTAINER_INPUT.String(), reader,
+	)
+	reader.Close()
+	if err != nil {
+		return
+	}
+	httpResponse.Body.Close()
+	return
+}
+
+// writeInput writes the records of the ATTACH_CONTAINER_INPUT call to w until
+// the standard input ends or ctx is done.
+func (e *Exec) writeInput(ctx context.Context, w io.Writer, containerID *mesos_v1.ContainerID) (err error) {
+	var send func(attach *mesos_v1_agent.Call_AttachContainerInput) error = func(
+		attach *mesos_v1_agent.Call_AttachContainerInput,
+	) error {
+		var callType mesos_v1_agent.Call_Type = mesos_v1_agent.Call_ATTACH_CONTAINER_INPUT
+		var b []byte
+		var err error
+		b, err = e.agent.client.encoding.marshal(&mesos_v1_agent.Call{Type: &callType, AttachContainerInput: attach})
+		if err != nil {
+			return err
+		}
+		return writeRecordioMessage(w, b)
+	}
+
+	var attachType mesos_v1_agent.Call_AttachContainerInput_Type = mesos_v1_agent.Call_AttachContainerInput_CONTAINER_ID
+	err = send(&mesos_v1_agent.Call_AttachContainerInput{Type: &attachType, ContainerId: containerID})
+	if err != nil {
+		return
+	}
+
+	var chunks chan []byte
+	if e.stdin != nil {
+		chunks = make(chan []byte)
+		go readChunks(ctx, e.stdin, chunks)
+	}
+	var resize <-chan WindowSize
+	if e.tty {
+		resize = e.resize
+	}
+	var ticker *time.Ticker = time.NewTicker(e.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		var processIO *mesos_v1_agent.ProcessIO
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case chunk, ok := <-chunks:
+			// An empty chunk tells the container that its input has ended
+			processIO = stdinProcessIO(chunk)
+			if !ok {
+				chunks = nil
+			}
+		case size := <-resize:
+			processIO = controlProcessIO(mesos_v1_agent.ProcessIO_Control_TTY_INFO)
+			processIO.Control.TtyInfo = &mesos_v1.TTYInfo{
+				WindowSize: &mesos_v1.TTYInfo_WindowSize{Rows: &size.Rows, Columns: &size.Columns},
+			}
+		case <-ticker.C:
+			var nanoseconds int64 = e.heartbeatInterval.Nanoseconds()
+			processIO = controlProcessIO(mesos_v1_agent.ProcessIO_Control_HEARTBEAT)
+			processIO.Control.Heartbeat = &mesos_v1_agent.ProcessIO_Control_Heartbeat{
+				Interval: &mesos_v1.DurationInfo{Nanoseconds: &nanoseconds},
+			}
+		}
+		var processIOType mesos_v1_agent.Call_AttachContainerInput_Type = mesos_v1_agent.Call_AttachContainerInput_PROCESS_IO
+		err = send(&mesos_v1_agent.Call_AttachContainerInput{Type: &processIOType, ProcessIo: processIO})
+		if err != nil {
+			return
+		}
+		if chunks == nil && e.stdin != nil {
+			// The input has ended
+			return
+		}
+	}
+}
+
+// readChunks sends what is read from r to chunks and closes chunks when r
+// ends.
+func readChunks(ctx context.Context, r io.Reader, chunks chan<- []byte) {
+	defer close(chunks)
+	for {
+		var buf []byte = make([]byte, 32*1024)
+		n, err := r.Read(buf)
+		if n > 0 {
+			select {
+			case chunks <- buf[:n]:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stdinProcessIO returns a ProcessIO with data for the standard input.
+func stdinProcessIO(data []byte) *mesos_v1_agent.ProcessIO {
+	var processIOType mesos_v1_agent.ProcessIO_Type = mesos_v1_agent.ProcessIO_DATA
+	var dataType mesos_v1_agent.ProcessIO_Data_Type = mesos_v1_agent.ProcessIO_Data_STDIN
+	if data == nil {
+		data = []byte{}
+	}
+	return &mesos_v1_agent.ProcessIO{
+		Type: &processIOType,
+		Data: &mesos_v1_agent.ProcessIO_Data{Type: &dataType, Data: data},
+	}
+}
+
+// controlProcessIO returns a ProcessIO with a control message of the given
+// type.
+func controlProcessIO(controlType mesos_v1_agent.ProcessIO_Control_Type) *mesos_v1_agent.ProcessIO {
+	var processIOType mesos_v1_agent.ProcessIO_Type = mesos_v1_agent.ProcessIO_CONTROL
+	return &mesos_v1_agent.ProcessIO{
+		Type:    &processIOType,
+		Control: &mesos_v1_agent.ProcessIO_Control{Type: &controlType},
+	}
+}
+
+// remove removes the nested container. It is not bound to the context of Run,
+// so that the container is removed even if Run was canceled.
+func (e *Exec) remove(containerID *mesos_v1.ContainerID) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(context.Background(), e.cleanupTimeout)
+	defer cancel()
+	e.agent.RemoveNestedContainer(ctx, &mesos_v1_agent.Call_RemoveNestedContainer{ContainerId: containerID})
+}
+
+// newContainerID returns a ContainerID with a random UUID, nested in parentID
+// if parentID is not nil.
+func newContainerID(parentID *mesos_v1.ContainerID) (containerID *mesos_v1.ContainerID, err error) {
+	var b []byte = make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+	// Version 4, variant 1
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var value string = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	containerID = &mesos_v1.ContainerID{Value: &value, Parent: parentID}
+	return
+}
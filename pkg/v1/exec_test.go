@@ -0,0 +1,312 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+)
+
+// fakeExecAgent is an agent that runs a nested container session which writes
+// a greeting, echoes its standard input to its standard output and exits with
+// exitStatus. If inputStatus is set, ATTACH_CONTAINER_INPUT is answered with
+// it instead.
+type fakeExecAgent struct {
+	*TestProtobufServer
+	t           *testing.T
+	inputStatus int
+
+	mu       sync.Mutex
+	controls []*mesos_v1_agent.ProcessIO_Control
+	input    chan []byte
+}
+
+func newExecAgent(t *testing.T, exitStatus int32) (fake *fakeExecAgent) {
+	fake = &fakeExecAgent{TestProtobufServer: NewTestProtobufServer(AgentClient), t: t, input: make(chan []byte)}
+	fake.HandleAgentCall(mesos_v1_agent.Call_LAUNCH_NESTED_CONTAINER_SESSION, fake.launchSession).
+		HandleAgentCall(mesos_v1_agent.Call_WAIT_NESTED_CONTAINER, func(
+			rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call,
+		) {
+			writeResponse(rw, &mesos_v1_agent.Response{
+				Type: mesos_v1_agent.Response_WAIT_NESTED_CONTAINER.Enum(),
+				WaitNestedContainer: &mesos_v1_agent.Response_WaitNestedContainer{
+					ExitStatus: &exitStatus, State: mesos_v1.TaskState_TASK_FAILED.Enum(),
+				},
+			})
+		}).
+		HandleStream(fake.attachInput).
+		Handle()
+	return
+}
+
+// launchSession streams the output of the session until its input ends.
+func (f *fakeExecAgent) launchSession(rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call) {
+	if req.Header.Get("Accept") != recordioContentType {
+		f.t.Errorf("expected Accept %s, got %s", recordioContentType, req.Header.Get("Accept"))
+	}
+	rw.Header().Set("Content-Type", recordioContentType)
+	f.writeOutput(rw, mesos_v1_agent.ProcessIO_Data_STDOUT, []byte("hello\n"))
+	f.writeOutput(rw, mesos_v1_agent.ProcessIO_Data_STDERR, []byte("warning\n"))
+	for {
+		select {
+		case data, ok := <-f.input:
+			if !ok {
+				return
+			}
+			f.writeOutput(rw, mesos_v1_agent.ProcessIO_Data_STDOUT, data)
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// launched returns the LAUNCH_NESTED_CONTAINER_SESSION call received.
+func (f *fakeExecAgent) launched() *mesos_v1_agent.Call_LaunchNestedContainerSession {
+	for _, call := range f.AgentCalls() {
+		if call.GetType() == mesos_v1_agent.Call_LAUNCH_NESTED_CONTAINER_SESSION {
+			return call.GetLaunchNestedContainerSession()
+		}
+	}
+	return nil
+}
+
+// removed returns the ID of the container removed by REMOVE_NESTED_CONTAINER.
+func (f *fakeExecAgent) removed() *mesos_v1.ContainerID {
+	for _, call := range f.AgentCalls() {
+		if call.GetType() == mesos_v1_agent.Call_REMOVE_NESTED_CONTAINER {
+			return call.GetRemoveNestedContainer().GetContainerId()
+		}
+	}
+	return nil
+}
+
+// attachInput reads the records of an ATTACH_CONTAINER_INPUT call and sends
+// the standard input to the session.
+func (f *fakeExecAgent) attachInput(rw http.ResponseWriter, req *http.Request) {
+	if f.inputStatus != 0 {
+		// Close the connection rather than wait for the rest of the input,
+		// which never ends
+		rw.Header().Set("Connection", "close")
+		rw.WriteHeader(f.inputStatus)
+		return
+	}
+	defer close(f.input)
+	reader := bufio.NewReader(req.Body)
+	for first := true; ; first = false {
+		msg, err := readRecordioMessage(reader)
+		if err != nil {
+			return
+		}
+		call := &mesos_v1_agent.Call{}
+		if err = proto.Unmarshal(msg, call); err != nil {
+			f.t.Error(err)
+			return
+		}
+		attach := call.GetAttachContainerInput()
+		if first {
+			if attach.GetType() != mesos_v1_agent.Call_AttachContainerInput_CONTAINER_ID || attach.GetContainerId() == nil {
+				f.t.Errorf("expected the container ID first, got %s", attach)
+			}
+			continue
+		}
+		processIO := attach.GetProcessIo()
+		if processIO.GetType() == mesos_v1_agent.ProcessIO_CONTROL {
+			f.mu.Lock()
+			f.controls = append(f.controls, processIO.GetControl())
+			f.mu.Unlock()
+			continue
+		}
+		if len(processIO.GetData().GetData()) == 0 {
+			return
+		}
+		f.input <- processIO.GetData().GetData()
+	}
+}
+
+func (f *fakeExecAgent) writeOutput(rw http.ResponseWriter, dataType mesos_v1_agent.ProcessIO_Data_Type, data []byte) {
+	processIOType := mesos_v1_agent.ProcessIO_DATA
+	msg, err := proto.Marshal(&mesos_v1_agent.ProcessIO{
+		Type: &processIOType,
+		Data: &mesos_v1_agent.ProcessIO_Data{Type: &dataType, Data: data},
+	})
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	if err = writeRecordioMessage(rw, msg); err != nil {
+		f.t.Error(err)
+	}
+	rw.(http.Flusher).Flush()
+}
+
+func newParentID() *mesos_v1.ContainerID {
+	value := "parent"
+	return &mesos_v1.ContainerID{Value: &value}
+}
+
+func TestExecRun(t *testing.T) {
+	fake := newExecAgent(t, 3<<8)
+	defer fake.Teardown()
+
+	var stdout, stderr bytes.Buffer
+	e, err := fake.Agent().NewExecBuilder(newParentID(), "/bin/cat", "-").
+		SetStdin(strings.NewReader("input\n")).
+		SetStdout(&stdout).
+		SetStderr(&stderr).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := e.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stdout.String() != "hello\ninput\n" {
+		t.Errorf("expected hello and the input on stdout, got %q", stdout.String())
+	}
+	if stderr.String() != "warning\n" {
+		t.Errorf("expected warning on stderr, got %q", stderr.String())
+	}
+	if status, ok := result.ExitStatus(); !ok || status != 3<<8 {
+		t.Errorf("expected exit status %d, got %d %t", 3<<8, status, ok)
+	}
+	if result.ExitCode() != 3 || result.State != mesos_v1.TaskState_TASK_FAILED {
+		t.Errorf("expected exit code 3 and TASK_FAILED, got %d %s", result.ExitCode(), result.State)
+	}
+
+	launched := fake.launched()
+	if launched.GetContainerId().GetParent().GetValue() != "parent" || launched.GetContainerId().GetValue() == "" {
+		t.Errorf("expected a container nested in parent, got %s", launched.GetContainerId())
+	}
+	if launched.GetCommand().GetValue() != "/bin/cat" || launched.GetCommand().GetShell() {
+		t.Errorf("unexpected command %s", launched.GetCommand())
+	}
+	if launched.GetContainer() != nil {
+		t.Errorf("expected no container info without a TTY, got %s", launched.GetContainer())
+	}
+	if !proto.Equal(fake.removed(), launched.GetContainerId()) {
+		t.Errorf("expected %s to be removed, got %s", launched.GetContainerId(), fake.removed())
+	}
+}
+
+func TestExecRunInputRejected(t *testing.T) {
+	fake := newExecAgent(t, 0)
+	defer fake.Teardown()
+	fake.inputStatus = http.StatusInternalServerError
+
+	// The standard input never ends, as with an interactive session
+	stdin, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	e, err := fake.Agent().NewExecBuilder(newParentID(), "/bin/cat", "-").SetStdin(stdin).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = e.Run(ctx)
+	var httpError HTTPError
+	if !errors.As(err, &httpError) || httpError.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected a 500 HTTPError, got %v", err)
+	}
+
+	if !proto.Equal(fake.removed(), fake.launched().GetContainerId()) {
+		t.Errorf("expected %s to be removed, got %s", fake.launched().GetContainerId(), fake.removed())
+	}
+}
+
+func TestExecTTYResize(t *testing.T) {
+	fake := newExecAgent(t, 0)
+	defer fake.Teardown()
+
+	resize := make(chan WindowSize, 1)
+	resize <- WindowSize{Rows: 50, Columns: 132}
+	stdin, stdinWriter := io.Pipe()
+	e, err := fake.Agent().NewExecBuilder(newParentID(), "/bin/sh").
+		SetStdin(stdin).
+		SetTTY(true).
+		SetWindowSize(WindowSize{Rows: 24, Columns: 80}).
+		SetResize(resize).
+		SetHeartbeatInterval(10 * time.Millisecond).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		// Leave time for the resize and a heartbeat to be sent
+		time.Sleep(100 * time.Millisecond)
+		stdinWriter.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := e.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode() != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode())
+	}
+
+	windowSize := fake.launched().GetContainer().GetTtyInfo().GetWindowSize()
+	if windowSize.GetRows() != 24 || windowSize.GetColumns() != 80 {
+		t.Errorf("expected a 24x80 terminal, got %s", windowSize)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	var resized, heartbeat bool
+	for _, control := range fake.controls {
+		switch control.GetType() {
+		case mesos_v1_agent.ProcessIO_Control_TTY_INFO:
+			size := control.GetTtyInfo().GetWindowSize()
+			resized = size.GetRows() == 50 && size.GetColumns() == 132
+		case mesos_v1_agent.ProcessIO_Control_HEARTBEAT:
+			heartbeat = true
+		}
+	}
+	if !resized || !heartbeat {
+		t.Errorf("expected a resize and a heartbeat, got %v", fake.controls)
+	}
+}
+
+func TestContainerResultExitCode(t *testing.T) {
+	status := func(s int32) *int32 { return &s }
+	tests := []struct {
+		result   ContainerResult
+		expected int
+	}{
+		{ContainerResult{}, -1},
+		{ContainerResult{exitStatus: status(0)}, 0},
+		{ContainerResult{exitStatus: status(1 << 8)}, 1},
+		{ContainerResult{exitStatus: status(9)}, 137},
+	}
+	for _, test := range tests {
+		if code := test.result.ExitCode(); code != test.expected {
+			t.Errorf("expected %d, got %d", test.expected, code)
+		}
+	}
+}
+
+func TestExecBuilderRejectsEmptyCommand(t *testing.T) {
+	a, err := NewAgentBuilder("http://127.0.0.1:5051").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.NewExecBuilder(newParentID()).Build(); err == nil {
+		t.Error("expected an error for an empty command")
+	}
+	if _, err = a.NewExecBuilder(nil, "/bin/ls").Build(); err == nil {
+		t.Error("expected an error for a missing parent")
+	}
+}
@@ -0,0 +1,324 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+	"github.com/miroswan/mesops/pkg/v1"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// sourceLabel is the label that tells master metrics from agent metrics
+	sourceLabel string = "source"
+	// hostLabel is the label holding the HOST:PORT of the master or agent
+	hostLabel string = "host"
+
+	sourceMaster string = "master"
+	sourceAgent  string = "agent"
+)
+
+var (
+	upDesc *prometheus.Desc = prometheus.NewDesc(
+		namespace+"_up",
+		"Whether the metrics of the master or agent were scraped successfully.",
+		[]string{sourceLabel, hostLabel}, nil,
+	)
+	scrapeDurationDesc *prometheus.Desc = prometheus.NewDesc(
+		namespace+"_scrape_duration_seconds",
+		"Duration of the scrape of the metrics of the master or agent.",
+		[]string{sourceLabel, hostLabel}, nil,
+	)
+	agentDiscoveryDesc *prometheus.Desc = prometheus.NewDesc(
+		namespace+"_agent_discovery_success",
+		"Whether the agents known to the master were listed successfully.",
+		nil, nil,
+	)
+)
+
+// Collector is a prometheus.Collector that scrapes the metrics of the leading
+// master and, unless disabled, of every agent known to it each time it is
+// collected. Every metric is labeled with its source, master or agent, and the
+// HOST:PORT it was scraped from. The Agent clients are kept between scrapes,
+// so that their connections are reused, for as long as the master knows of the
+// agents. Build a Collector with a CollectorBuilder.
+type Collector struct {
+	master         *v1.Master
+	translator     *Translator
+	timeout        time.Duration
+	scrapeAgents   bool
+	maxConcurrency int
+	mu             sync.Mutex
+	agents         map[string]*v1.Agent
+}
+
+// CollectorBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct. Create a CollectorBuilder with
+// NewCollectorBuilder
+type CollectorBuilder struct {
+	collector *Collector
+}
+
+// NewCollectorBuilder returns a pointer to a CollectorBuilder for the given
+// Master. Agents are reached with the settings of the Master, see
+// Master.NewAgentBuilder.
+func NewCollectorBuilder(master *v1.Master) *CollectorBuilder {
+	return &CollectorBuilder{
+		collector: &Collector{
+			master:         master,
+			timeout:        10 * time.Second,
+			scrapeAgents:   true,
+			maxConcurrency: 8,
+			agents:         map[string]*v1.Agent{},
+		},
+	}
+}
+
+// SetTranslator sets the Translator of the Collector and returns a pointer to
+// the CollectorBuilder. If SetTranslator is not called, a Translator with the
+// DefaultRules is used.
+//
+// e.g.
+//
+// 	var b *CollectorBuilder = NewCollectorBuilder(m).SetTranslator(myTranslator)
+func (b *CollectorBuilder) SetTranslator(translator *Translator) *CollectorBuilder {
+	b.collector.translator = translator
+	return b
+}
+
+// SetTimeout sets the time allowed for a whole scrape and returns a pointer to
+// the CollectorBuilder. Masters and agents that have not responded in time are
+// reported as down. If SetTimeout is not called, it will be set to 10 seconds.
+//
+// e.g.
+//
+// 	var b *CollectorBuilder = NewCollectorBuilder(m).SetTimeout(5 * time.Second)
+func (b *CollectorBuilder) SetTimeout(timeout time.Duration) *CollectorBuilder {
+	b.collector.timeout = timeout
+	return b
+}
+
+// SetScrapeAgents sets whether the agents known to the master are scraped and
+// returns a pointer to the CollectorBuilder. If SetScrapeAgents is not called,
+// agents are scraped.
+//
+// e.g.
+//
+// 	var b *CollectorBuilder = NewCollectorBuilder(m).SetScrapeAgents(false)
+func (b *CollectorBuilder) SetScrapeAgents(scrapeAgents bool) *CollectorBuilder {
+	b.collector.scrapeAgents = scrapeAgents
+	return b
+}
+
+// SetMaxConcurrency sets the maximum number of masters and agents scraped at
+// the same time and returns a pointer to the CollectorBuilder. If
+// SetMaxConcurrency is not called, it will be set to 8.
+//
+// e.g.
+//
+// 	var b *CollectorBuilder = NewCollectorBuilder(m).SetMaxConcurrency(32)
+func (b *CollectorBuilder) SetMaxConcurrency(maxConcurrency int) *CollectorBuilder {
+	b.collector.maxConcurrency = maxConcurrency
+	return b
+}
+
+// Build returns a pointer to a constructed Collector.
+func (b *CollectorBuilder) Build() (c *Collector, err error) {
+	if b.collector.master == nil {
+		err = errors.New("master must not be nil")
+		return
+	}
+	if b.collector.timeout <= 0 {
+		err = errors.New("timeout must be greater than 0")
+		return
+	}
+	if b.collector.maxConcurrency < 1 {
+		err = errors.New("maxConcurrency must be at least 1")
+		return
+	}
+	if b.collector.translator == nil {
+		b.collector.translator, err = NewTranslator(DefaultRules...)
+		if err != nil {
+			return
+		}
+	}
+	for _, label := range b.collector.translator.labelNames() {
+		if label == sourceLabel || label == hostLabel {
+			err = fmt.Errorf("the label %q is reserved by the Collector", label)
+			return
+		}
+	}
+	c = b.collector
+	return
+}
+
+// target is a master or agent to scrape.
+type target struct {
+	source     string
+	host       string
+	getMetrics func(ctx context.Context) (metrics []*mesos_v1.Metric, err error)
+}
+
+// Describe implements prometheus.Collector. The metrics depend on the cluster,
+// so none are described and the Collector is unchecked.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var targets []target = c.targets(ctx, ch)
+	var wg sync.WaitGroup
+	var sem chan struct{} = make(chan struct{}, c.maxConcurrency)
+	for _, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.scrape(ctx, t, ch)
+		}(t)
+	}
+	wg.Wait()
+}
+
+// targets returns the leading master and the agents known to it.
+func (c *Collector) targets(ctx context.Context, ch chan<- prometheus.Metric) (targets []target) {
+	response, err := c.master.GetMaster(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, sourceMaster, "")
+		return
+	}
+	var masterInfo *mesos_v1.MasterInfo = response.GetGetMaster().GetMasterInfo()
+	var hostname string = masterInfo.GetHostname()
+	if hostname == "" {
+		hostname, _ = v1.Uint32toIPv4(masterInfo.GetIp())
+	}
+	targets = append(targets, target{
+		source: sourceMaster,
+		host:   net.JoinHostPort(hostname, strconv.Itoa(int(masterInfo.GetPort()))),
+		getMetrics: func(ctx context.Context) (metrics []*mesos_v1.Metric, err error) {
+			var response *mesos_v1_master.Response
+			response, err = c.master.GetMetrics(ctx)
+			metrics = response.GetGetMetrics().GetMetrics()
+			return
+		},
+	})
+	if !c.scrapeAgents {
+		return
+	}
+
+	response, err = c.master.GetAgents(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(agentDiscoveryDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(agentDiscoveryDesc, prometheus.GaugeValue, 1)
+	var known map[string]bool = map[string]bool{}
+	for _, agent := range response.GetGetAgents().GetAgents() {
+		var agentInfo *mesos_v1.AgentInfo = agent.GetAgentInfo()
+		var port int32 = agentInfo.GetPort()
+		if port == 0 {
+			port = 5051
+		}
+		var host string = net.JoinHostPort(agentInfo.GetHostname(), strconv.Itoa(int(port)))
+		known[host] = true
+		targets = append(targets, target{
+			source: sourceAgent,
+			host:   host,
+			getMetrics: func(ctx context.Context) (metrics []*mesos_v1.Metric, err error) {
+				var a *v1.Agent
+				a, err = c.agent(host, agentInfo)
+				if err != nil {
+					return
+				}
+				var response *mesos_v1_agent.Response
+				response, err = a.GetMetrics(ctx)
+				metrics = response.GetGetMetrics().GetMetrics()
+				return
+			},
+		})
+	}
+	c.forgetAgents(known)
+	return
+}
+
+// agent returns the Agent client for the agent at host, building it from
+// agentInfo the first time the agent is scraped.
+func (c *Collector) agent(host string, agentInfo *mesos_v1.AgentInfo) (a *v1.Agent, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a = c.agents[host]
+	if a != nil {
+		return
+	}
+	a, err = c.master.NewAgentBuilder(agentInfo).Build()
+	if err != nil {
+		return
+	}
+	c.agents[host] = a
+	return
+}
+
+// forgetAgents drops the Agent clients of the agents that are no longer
+// known to the master.
+func (c *Collector) forgetAgents(known map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for host := range c.agents {
+		if !known[host] {
+			delete(c.agents, host)
+		}
+	}
+}
+
+// scrape sends the metrics of t to ch, followed by whether the scrape
+// succeeded and how long it took.
+func (c *Collector) scrape(ctx context.Context, t target, ch chan<- prometheus.Metric) {
+	var start time.Time = time.Now()
+	metrics, err := t.getMetrics(ctx)
+	var up float64
+	if err == nil {
+		up = 1
+		var constLabels prometheus.Labels = prometheus.Labels{sourceLabel: t.source, hostLabel: t.host}
+		for _, pm := range c.translator.Translate(metrics, constLabels) {
+			ch <- pm
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, t.source, t.host)
+	ch <- prometheus.MustNewConstMetric(
+		scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), t.source, t.host,
+	)
+}
@@ -0,0 +1,209 @@
+package exporter
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+	"github.com/miroswan/mesops/pkg/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newMesosServer returns a server that answers calls with the response
+// returned by respond.
+func newMesosServer(t *testing.T, call proto.Message, respond func() proto.Message) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = proto.Unmarshal(body, call); err != nil {
+			t.Error(err)
+		}
+		output, err := proto.Marshal(respond())
+		if err != nil {
+			t.Error(err)
+		}
+		rw.Write(output)
+	}))
+}
+
+func hostPort(t *testing.T, server *httptest.Server) (host string, port uint32) {
+	host, p, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port64, err := strconv.ParseUint(p, 10, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port = uint32(port64)
+	return
+}
+
+func gather(t *testing.T, c *Collector) map[string]*dto.MetricFamily {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+func labels(m *dto.Metric) map[string]string {
+	result := map[string]string{}
+	for _, pair := range m.GetLabel() {
+		result[pair.GetName()] = pair.GetValue()
+	}
+	return result
+}
+
+func TestCollector(t *testing.T) {
+	agentCall := &mesos_v1_agent.Call{}
+	agentServer := newMesosServer(t, agentCall, func() proto.Message {
+		return &mesos_v1_agent.Response{
+			Type: mesos_v1_agent.Response_GET_METRICS.Enum(),
+			GetMetrics: &mesos_v1_agent.Response_GetMetrics{Metrics: []*mesos_v1.Metric{
+				{Name: proto.String("system/load_1min"), Value: proto.Float64(0.5)},
+			}},
+		}
+	})
+	defer agentServer.Close()
+
+	// An agent that has gone away
+	goneServer := httptest.NewServer(http.NotFoundHandler())
+	goneServer.Close()
+
+	var masterServer *httptest.Server
+	masterCall := &mesos_v1_master.Call{}
+	masterServer = newMesosServer(t, masterCall, func() proto.Message {
+		switch masterCall.GetType() {
+		case mesos_v1_master.Call_GET_MASTER:
+			host, port := hostPort(t, masterServer)
+			return &mesos_v1_master.Response{
+				Type: mesos_v1_master.Response_GET_MASTER.Enum(),
+				GetMaster: &mesos_v1_master.Response_GetMaster{MasterInfo: &mesos_v1.MasterInfo{
+					Id: proto.String("master"), Ip: proto.Uint32(0), Port: proto.Uint32(port), Hostname: proto.String(host),
+				}},
+			}
+		case mesos_v1_master.Call_GET_AGENTS:
+			var agents []*mesos_v1_master.Response_GetAgents_Agent
+			for _, server := range []*httptest.Server{agentServer, goneServer} {
+				host, port := hostPort(t, server)
+				agents = append(agents, &mesos_v1_master.Response_GetAgents_Agent{
+					AgentInfo: &mesos_v1.AgentInfo{Hostname: proto.String(host), Port: proto.Int32(int32(port))},
+					Active:    proto.Bool(true),
+					Version:   proto.String("1.11.0"),
+				})
+			}
+			return &mesos_v1_master.Response{
+				Type:      mesos_v1_master.Response_GET_AGENTS.Enum(),
+				GetAgents: &mesos_v1_master.Response_GetAgents{Agents: agents},
+			}
+		}
+		return &mesos_v1_master.Response{
+			Type: mesos_v1_master.Response_GET_METRICS.Enum(),
+			GetMetrics: &mesos_v1_master.Response_GetMetrics{Metrics: []*mesos_v1.Metric{
+				{Name: proto.String("system/load_1min"), Value: proto.Float64(1.5)},
+				{Name: proto.String("allocator/mesos/roles/web/shares/dominant"), Value: proto.Float64(0.25)},
+			}},
+		}
+	})
+	defer masterServer.Close()
+
+	m, err := v1.NewMasterBuilder(masterServer.URL).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCollectorBuilder(m).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	families := gather(t, c)
+
+	masterHost := masterServer.Listener.Addr().String()
+	agentHost := agentServer.Listener.Addr().String()
+	goneHost := goneServer.Listener.Addr().String()
+
+	load := map[string]float64{}
+	for _, m := range families["mesos_system_load_1min"].GetMetric() {
+		load[labels(m)["source"]+" "+labels(m)["host"]] = m.GetUntyped().GetValue()
+	}
+	if load["master "+masterHost] != 1.5 || load["agent "+agentHost] != 0.5 || len(load) != 2 {
+		t.Errorf("unexpected mesos_system_load_1min: %v", load)
+	}
+
+	shares := families["mesos_allocator_mesos_roles_shares_dominant"].GetMetric()
+	if len(shares) != 1 || labels(shares[0])["role"] != "web" {
+		t.Errorf("unexpected mesos_allocator_mesos_roles_shares_dominant: %v", shares)
+	}
+
+	up := map[string]float64{}
+	for _, m := range families["mesos_up"].GetMetric() {
+		up[labels(m)["host"]] = m.GetGauge().GetValue()
+	}
+	if up[masterHost] != 1 || up[agentHost] != 1 || up[goneHost] != 0 {
+		t.Errorf("unexpected mesos_up: %v", up)
+	}
+
+	discovery := families["mesos_agent_discovery_success"].GetMetric()
+	if len(discovery) != 1 || discovery[0].GetGauge().GetValue() != 1 {
+		t.Errorf("unexpected mesos_agent_discovery_success: %v", discovery)
+	}
+
+	// The Agent clients are reused by the next scrape
+	agent := c.agents[agentHost]
+	if agent == nil || len(c.agents) != 2 {
+		t.Fatalf("unexpected agents %v", c.agents)
+	}
+	gather(t, c)
+	if c.agents[agentHost] != agent || len(c.agents) != 2 {
+		t.Errorf("expected the agent client to be reused, got %v", c.agents)
+	}
+}
+
+func TestCollectorMasterDown(t *testing.T) {
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	m, err := v1.NewMasterBuilder(gone.URL).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCollectorBuilder(m).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	families := gather(t, c)
+	up := families["mesos_up"].GetMetric()
+	if len(up) != 1 || up[0].GetGauge().GetValue() != 0 || labels(up[0])["source"] != "master" {
+		t.Errorf("unexpected mesos_up: %v", up)
+	}
+}
+
+func TestCollectorBuilderReservedLabel(t *testing.T) {
+	m, err := v1.NewMasterBuilder("http://127.0.0.1:5050").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	translator, err := NewTranslator("master/<host>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewCollectorBuilder(m).SetTranslator(translator).Build(); err == nil {
+		t.Error("expected an error")
+	}
+}
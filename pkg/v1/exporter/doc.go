@@ -0,0 +1,43 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package exporter turns the metrics of Mesos masters and agents into
+// Prometheus metrics.
+//
+// Mesos reports its metrics as a flat list of name and value pairs, such as
+// master/cpus_percent or master/frameworks/<name>/<id>/messages_received. A
+// Translator sanitizes each name into a valid Prometheus metric name prefixed
+// with mesos_, and Rules move the variable parts of a name, such as framework
+// IDs and roles, into labels. A Collector scrapes the leading master and every
+// agent known to it and is registered with a prometheus.Registerer.
+//
+// e.g.
+//
+// 	var m *v1.Master
+// 	m, err = v1.NewMasterBuilder("http://127.0.0.1:5050").Build()
+// 	var c *Collector
+// 	c, err = NewCollectorBuilder(m).SetTimeout(5 * time.Second).Build()
+// 	prometheus.MustRegister(c)
+// 	http.Handle("/metrics", promhttp.Handler())
+//
+// See cmd/mesos-exporter for a complete exporter.
+package exporter
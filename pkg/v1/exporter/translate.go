@@ -0,0 +1,228 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// namespace prefixes the name of every translated metric.
+const namespace string = "mesos"
+
+// labelNamePattern matches valid Prometheus label names.
+var labelNamePattern *regexp.Regexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Rule is a pattern that matches the leading segments of Mesos metric names.
+// A segment of the pattern written as <label> matches any segment, which
+// becomes the value of the label. The other segments must match exactly. The
+// segments of a name that are not captured form the name of the Prometheus
+// metric.
+//
+// e.g.
+//
+// 	// master/frameworks/marathon/5d3a-0000/messages_received becomes
+// 	// mesos_master_frameworks_messages_received{framework_name="marathon",framework_id="5d3a-0000"}
+// 	var r Rule = "master/frameworks/<framework_name>/<framework_id>"
+type Rule string
+
+// DefaultRules cover the per-framework metrics of the master, the per-role
+// metrics of the allocator and the per-resource-provider metrics of the agent.
+var DefaultRules []Rule = []Rule{
+	"master/frameworks/<framework_name>/<framework_id>",
+	"allocator/mesos/roles/<role>",
+	"allocator/mesos/quota/roles/<role>/resources/<resource>",
+	"allocator/mesos/quota/roles/<role>",
+	"allocator/mesos/offer_filters/roles/<role>",
+	"resource_providers/<resource_provider>",
+}
+
+// rule is a parsed Rule. labels holds the label name of each captured segment
+// and is empty for the segments that must match exactly.
+type rule struct {
+	segments []string
+	labels   []string
+}
+
+// parseRule parses and validates r.
+func parseRule(r Rule) (parsed rule, err error) {
+	var labels map[string]bool = map[string]bool{}
+	parsed.segments = strings.Split(string(r), "/")
+	parsed.labels = make([]string, len(parsed.segments))
+	for i, segment := range parsed.segments {
+		if segment == "" {
+			err = fmt.Errorf("rule %q has an empty segment", r)
+			return
+		}
+		if !strings.HasPrefix(segment, "<") || !strings.HasSuffix(segment, ">") {
+			continue
+		}
+		var label string = segment[1 : len(segment)-1]
+		if !labelNamePattern.MatchString(label) {
+			err = fmt.Errorf("rule %q has an invalid label name %q", r, label)
+			return
+		}
+		if labels[label] {
+			err = fmt.Errorf("rule %q has the label %q more than once", r, label)
+			return
+		}
+		labels[label] = true
+		parsed.labels[i] = label
+	}
+	return
+}
+
+// match reports whether the rule matches the leading segments of a name.
+func (r rule) match(segments []string) bool {
+	if len(segments) <= len(r.segments) {
+		return false
+	}
+	for i, segment := range r.segments {
+		if r.labels[i] == "" && segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Translator turns Mesos metrics into Prometheus metrics. Create a Translator
+// with NewTranslator.
+type Translator struct {
+	rules []rule
+}
+
+// NewTranslator returns a pointer to a Translator that applies the given Rules
+// in order. The first Rule that matches a name is used, so more specific Rules
+// must come first. Names that no Rule matches are only sanitized.
+//
+// e.g.
+//
+// 	var t *Translator
+// 	t, err = NewTranslator(append(DefaultRules, "master/custom/<team>")...)
+func NewTranslator(rules ...Rule) (t *Translator, err error) {
+	t = &Translator{}
+	for _, r := range rules {
+		var parsed rule
+		parsed, err = parseRule(r)
+		if err != nil {
+			t = nil
+			return
+		}
+		t.rules = append(t.rules, parsed)
+	}
+	return
+}
+
+// Translate returns a Prometheus metric for each Mesos metric. Every metric
+// has the given constant labels in addition to the labels of the Rule that
+// matches it. Mesos does not report the type of its metrics, so all of them
+// are untyped. When two names translate to the same series, the first one is
+// kept.
+func (t *Translator) Translate(metrics []*mesos_v1.Metric, constLabels prometheus.Labels) (pms []prometheus.Metric) {
+	var descs map[string]*prometheus.Desc = map[string]*prometheus.Desc{}
+	var seen map[string]bool = map[string]bool{}
+	for _, metric := range metrics {
+		name, help, labelNames, labelValues := t.translate(metric.GetName())
+		var series string = name + "\xff" + strings.Join(labelValues, "\xff")
+		if seen[series] {
+			continue
+		}
+		seen[series] = true
+
+		var desc *prometheus.Desc = descs[name]
+		if desc == nil {
+			desc = prometheus.NewDesc(name, help, labelNames, constLabels)
+			descs[name] = desc
+		}
+		pm, err := prometheus.NewConstMetric(desc, prometheus.UntypedValue, metric.GetValue(), labelValues...)
+		if err != nil {
+			pm = prometheus.NewInvalidMetric(desc, err)
+		}
+		pms = append(pms, pm)
+	}
+	return
+}
+
+// labelNames returns the label names used by the Rules of the Translator.
+func (t *Translator) labelNames() (names []string) {
+	for _, r := range t.rules {
+		for _, label := range r.labels {
+			if label != "" {
+				names = append(names, label)
+			}
+		}
+	}
+	return
+}
+
+// translate returns the Prometheus name, the help and the labels of the Mesos
+// metric with the given name.
+func (t *Translator) translate(mesosName string) (
+	name string, help string, labelNames []string, labelValues []string,
+) {
+	var segments []string = strings.Split(mesosName, "/")
+	var matched rule
+	for _, r := range t.rules {
+		if r.match(segments) {
+			matched = r
+			break
+		}
+	}
+
+	var nameSegments []string = []string{namespace}
+	var helpSegments []string
+	for i, segment := range segments {
+		if i < len(matched.labels) && matched.labels[i] != "" {
+			// Mesos URL encodes the names of frameworks
+			value, err := url.PathUnescape(segment)
+			if err != nil {
+				value = segment
+			}
+			labelNames = append(labelNames, matched.labels[i])
+			labelValues = append(labelValues, value)
+			helpSegments = append(helpSegments, "<"+matched.labels[i]+">")
+			continue
+		}
+		nameSegments = append(nameSegments, sanitize(segment))
+		helpSegments = append(helpSegments, segment)
+	}
+	name = strings.Join(nameSegments, "_")
+	help = "Mesos metric " + strings.Join(helpSegments, "/")
+	return
+}
+
+// sanitize replaces the characters that are not allowed in Prometheus metric
+// names with underscores, e.g. p99.9 becomes p99_9.
+func sanitize(segment string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, segment)
+}
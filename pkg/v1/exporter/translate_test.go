@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTranslatorNames(t *testing.T) {
+	translator, err := NewTranslator(DefaultRules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		mesosName string
+		name      string
+		labels    map[string]string
+	}{
+		{"master/cpus_percent", "mesos_master_cpus_percent", map[string]string{}},
+		{"registrar/state_store_ms/p99.9", "mesos_registrar_state_store_ms_p99_9", map[string]string{}},
+		{
+			"master/frameworks/my%20framework/5d3a-0000/tasks/active/task_running",
+			"mesos_master_frameworks_tasks_active_task_running",
+			map[string]string{"framework_name": "my framework", "framework_id": "5d3a-0000"},
+		},
+		{
+			"allocator/mesos/roles/web/shares/dominant",
+			"mesos_allocator_mesos_roles_shares_dominant",
+			map[string]string{"role": "web"},
+		},
+		{
+			"allocator/mesos/quota/roles/web/resources/cpus/guarantee",
+			"mesos_allocator_mesos_quota_roles_resources_guarantee",
+			map[string]string{"role": "web", "resource": "cpus"},
+		},
+	}
+	for _, row := range table {
+		name, _, labelNames, labelValues := translator.translate(row.mesosName)
+		if name != row.name {
+			t.Errorf("%s: expected %s, got %s", row.mesosName, row.name, name)
+		}
+		if len(labelNames) != len(row.labels) {
+			t.Errorf("%s: expected labels %v, got %v", row.mesosName, row.labels, labelNames)
+			continue
+		}
+		for i, labelName := range labelNames {
+			if row.labels[labelName] != labelValues[i] {
+				t.Errorf("%s: expected %s=%s, got %s", row.mesosName, labelName, row.labels[labelName], labelValues[i])
+			}
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	translator, err := NewTranslator(DefaultRules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := []*mesos_v1.Metric{
+		{Name: proto.String("master/frameworks/a/1/messages_received"), Value: proto.Float64(3)},
+		{Name: proto.String("master/frameworks/b/2/messages_received"), Value: proto.Float64(4)},
+		// Translates to the same series as the metric above
+		{Name: proto.String("master/frameworks/b/2/messages.received"), Value: proto.Float64(5)},
+	}
+	pms := translator.Translate(metrics, prometheus.Labels{"source": "master"})
+	if len(pms) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(pms))
+	}
+	var values []float64
+	for _, pm := range pms {
+		var m dto.Metric
+		if err := pm.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.GetLabel()) != 3 {
+			t.Errorf("expected 3 labels, got %v", m.GetLabel())
+		}
+		values = append(values, m.GetUntyped().GetValue())
+	}
+	if values[0] != 3 || values[1] != 4 {
+		t.Errorf("expected [3 4], got %v", values)
+	}
+	if !strings.Contains(pms[0].Desc().String(), "master/frameworks/<framework_name>/<framework_id>/messages_received") {
+		t.Errorf("expected the help to name the Mesos metric, got %s", pms[0].Desc())
+	}
+}
+
+func TestNewTranslatorInvalidRules(t *testing.T) {
+	for _, r := range []Rule{"master//<role>", "master/<bad-label>", "master/<role>/<role>"} {
+		if _, err := NewTranslator(r); err == nil {
+			t.Errorf("%s: expected an error", r)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// fileSource reads and lists the files of a master or agent.
+type fileSource interface {
+	readFile(ctx context.Context, path string, offset int64, length int64) (data []byte, size int64, err error)
+	listFiles(ctx context.Context, path string) (fileInfos []*mesos_v1.FileInfo, err error)
+}
+
+// readFile reads up to length bytes of path at offset and returns them with
+// the size of the file.
+func (m *Master) readFile(ctx context.Context, path string, offset int64, length int64) (
+	data []byte, size int64, err error,
+) {
+	var uoffset, ulength uint64 = uint64(offset), uint64(length)
+	var response *mesos_v1_master.Response
+	response, err = m.ReadFile(ctx, &mesos_v1_master.Call_ReadFile{Path: &path, Offset: &uoffset, Length: &ulength})
+	data = response.GetReadFile().GetData()
+	size = int64(response.GetReadFile().GetSize())
+	return
+}
+
+// listFiles lists the directory, or the file, at path.
+func (m *Master) listFiles(ctx context.Context, path string) (fileInfos []*mesos_v1.FileInfo, err error) {
+	var response *mesos_v1_master.Response
+	response, err = m.ListFiles(ctx, &mesos_v1_master.Call_ListFiles{Path: &path})
+	fileInfos = response.GetListFiles().GetFileInfos()
+	return
+}
+
+// readFile reads up to length bytes of path at offset and returns them with
+// the size of the file.
+func (a *Agent) readFile(ctx context.Context, path string, offset int64, length int64) (
+	data []byte, size int64, err error,
+) {
+	var uoffset, ulength uint64 = uint64(offset), uint64(length)
+	var response *mesos_v1_agent.Response
+	response, err = a.ReadFile(ctx, &mesos_v1_agent.Call_ReadFile{Path: &path, Offset: &uoffset, Length: &ulength})
+	data = response.GetReadFile().GetData()
+	size = int64(response.GetReadFile().GetSize())
+	return
+}
+
+// listFiles lists the directory, or the file, at path.
+func (a *Agent) listFiles(ctx context.Context, path string) (fileInfos []*mesos_v1.FileInfo, err error) {
+	var response *mesos_v1_agent.Response
+	response, err = a.ListFiles(ctx, &mesos_v1_agent.Call_ListFiles{Path: &path})
+	fileInfos = response.GetListFiles().GetFileInfos()
+	return
+}
+
+// isDir reports whether a FileInfo describes a directory. Mesos reports the
+// st_mode of the file.
+func isDir(fileInfo *mesos_v1.FileInfo) bool {
+	const typeMask, typeDir uint32 = 0170000, 0040000
+	return fileInfo.GetMode()&typeMask == typeDir
+}
+
+// FileReader is an io.ReadSeeker over a file of a master or agent that reads
+// the file in chunks with ReadFile. Build a FileReader with the
+// FileReaderBuilder returned by Master.NewFileReaderBuilder or
+// Agent.NewFileReaderBuilder.
+type FileReader struct {
+	ctx       context.Context
+	source    fileSource
+	path      string
+	chunkSize int64
+
+	offset int64
+	size   int64
+	buf    []byte
+}
+
+// FileReaderBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct.
+type FileReaderBuilder struct {
+	reader *FileReader
+}
+
+// NewFileReaderBuilder returns a pointer to a FileReaderBuilder for the file
+// of the master at the given virtual path.
+func (m *Master) NewFileReaderBuilder(path string) *FileReaderBuilder {
+	return newFileReaderBuilder(m, path)
+}
+
+// NewFileReaderBuilder returns a pointer to a FileReaderBuilder for the file
+// of the agent at the given virtual path.
+//
+// e.g.
+//
+// 	var r *FileReader
+// 	r, err = a.NewFileReaderBuilder("/slave/log").SetContext(ctx).Build()
+// 	_, err = r.Seek(-1024, io.SeekEnd)
+// 	_, err = io.Copy(os.Stdout, r)
+func (a *Agent) NewFileReaderBuilder(path string) *FileReaderBuilder {
+	return newFileReaderBuilder(a, path)
+}
+
+func newFileReaderBuilder(source fileSource, path string) *FileReaderBuilder {
+	return &FileReaderBuilder{
+		reader: &FileReader{
+			ctx:       context.Background(),
+			source:    source,
+			path:      path,
+			chunkSize: 64 * 1024,
+			size:      -1,
+		},
+	}
+}
+
+// SetContext sets the context of the ReadFile and ListFiles calls made by the
+// FileReader and returns a pointer to the FileReaderBuilder. If SetContext is
+// not called, context.Background is used.
+//
+// e.g.
+//
+// 	var b *FileReaderBuilder = a.NewFileReaderBuilder("/slave/log").SetContext(ctx)
+func (b *FileReaderBuilder) SetContext(ctx context.Context) *FileReaderBuilder {
+	b.reader.ctx = ctx
+	return b
+}
+
+// SetChunkSize sets the maximum number of bytes read with each ReadFile call
+// and returns a pointer to the FileReaderBuilder. If SetChunkSize is not
+// called, it will be set to 64KiB.
+//
+// e.g.
+//
+// 	var b *FileReaderBuilder = a.NewFileReaderBuilder("/slave/log").SetChunkSize(1024 * 1024)
+func (b *FileReaderBuilder) SetChunkSize(chunkSize int64) *FileReaderBuilder {
+	b.reader.chunkSize = chunkSize
+	return b
+}
+
+// Build returns a pointer to a constructed FileReader.
+func (b *FileReaderBuilder) Build() (r *FileReader, err error) {
+	switch {
+	case b.reader.ctx == nil:
+		err = errors.New("ctx must not be nil")
+	case b.reader.path == "":
+		err = errors.New("path must not be empty")
+	case b.reader.chunkSize <= 0:
+		err = errors.New("chunkSize must be greater than 0")
+	}
+	if err != nil {
+		return
+	}
+	r = b.reader
+	return
+}
+
+// Size returns the size of the file as reported by ListFiles. The size is
+// looked up once.
+func (r *FileReader) Size() (size int64, err error) {
+	if r.size >= 0 {
+		size = r.size
+		return
+	}
+	var fileInfos []*mesos_v1.FileInfo
+	fileInfos, err = r.source.listFiles(r.ctx, r.path)
+	if err != nil {
+		return
+	}
+	for _, fileInfo := range fileInfos {
+		if fileInfo.GetPath() == r.path && !isDir(fileInfo) {
+			r.size = int64(fileInfo.GetSize())
+			size = r.size
+			return
+		}
+	}
+	err = fmt.Errorf("%s is not a file", r.path)
+	return
+}
+
+// Read implements io.Reader.
+func (r *FileReader) Read(p []byte) (n int, err error) {
+	if len(r.buf) == 0 {
+		var data []byte
+		data, _, err = r.source.readFile(r.ctx, r.path, r.offset, r.chunkSize)
+		if err != nil {
+			return
+		}
+		if len(data) == 0 {
+			err = io.EOF
+			return
+		}
+		r.buf = data
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.offset += int64(n)
+	return
+}
+
+// Seek implements io.Seeker. Seeking relative to io.SeekEnd looks up the size
+// of the file with ListFiles.
+func (r *FileReader) Seek(offset int64, whence int) (position int64, err error) {
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = r.offset + offset
+	case io.SeekEnd:
+		var size int64
+		size, err = r.Size()
+		if err != nil {
+			return
+		}
+		position = size + offset
+	default:
+		err = fmt.Errorf("invalid whence %d", whence)
+		return
+	}
+	if position < 0 {
+		err = errors.New("negative position")
+		position = r.offset
+		return
+	}
+	if position != r.offset {
+		r.buf = nil
+		r.offset = position
+	}
+	return
+}
+
+// DownloadedFile describes a file written by DownloadDirectory.
+type DownloadedFile struct {
+	// Path is the slash separated path of the file relative to the
+	// downloaded directory.
+	Path string
+	// Size is the number of bytes written.
+	Size int64
+	// SHA256 is the hex encoded SHA-256 checksum of the bytes written.
+	SHA256 string
+}
+
+// DownloadDirectory recursively copies the directory of the master at the
+// given virtual path into localDir, see Agent.DownloadDirectory.
+func (m *Master) DownloadDirectory(ctx context.Context, path string, localDir string) (
+	files []DownloadedFile, err error,
+) {
+	files, err = downloadDirectory(ctx, m, path, localDir)
+	return
+}
+
+// DownloadDirectory recursively copies the directory of the agent at the
+// given virtual path, such as the sandbox of an executor, into localDir. The
+// permissions and modification times of the files are kept. It returns the
+// size and SHA-256 checksum of every file written, which WriteSHA256Sums
+// turns into a manifest. Files that grow while they are copied, such as the
+// logs of a running task, are copied up to the size they were listed with.
+//
+// e.g.
+//
+// 	var files []DownloadedFile
+// 	files, err = a.DownloadDirectory(ctx, "/frameworks/"+frameworkID+"/executors/"+executorID+"/runs/latest", "sandbox")
+func (a *Agent) DownloadDirectory(ctx context.Context, path string, localDir string) (
+	files []DownloadedFile, err error,
+) {
+	files, err = downloadDirectory(ctx, a, path, localDir)
+	return
+}
+
+func downloadDirectory(ctx context.Context, source fileSource, dir string, localDir string) (
+	files []DownloadedFile, err error,
+) {
+	dir = path.Clean(dir)
+	var fileInfos []*mesos_v1.FileInfo
+	fileInfos, err = source.listFiles(ctx, dir)
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(localDir, 0755)
+	if err != nil {
+		return
+	}
+	for _, fileInfo := range fileInfos {
+		var name string = path.Base(fileInfo.GetPath())
+		if path.Dir(fileInfo.GetPath()) != dir || name == "." || name == ".." || name == "/" {
+			err = fmt.Errorf("unexpected path %s in %s", fileInfo.GetPath(), dir)
+			return
+		}
+		var localPath string = filepath.Join(localDir, name)
+		if isDir(fileInfo) {
+			var nested []DownloadedFile
+			nested, err = downloadDirectory(ctx, source, fileInfo.GetPath(), localPath)
+			if err != nil {
+				return
+			}
+			for _, file := range nested {
+				file.Path = name + "/" + file.Path
+				files = append(files, file)
+			}
+			continue
+		}
+		var file DownloadedFile
+		file, err = downloadFile(ctx, source, fileInfo, localPath)
+		if err != nil {
+			return
+		}
+		file.Path = name
+		files = append(files, file)
+	}
+	return
+}
+
+// downloadFile copies a file into localPath and returns its size and checksum.
+func downloadFile(ctx context.Context, source fileSource, fileInfo *mesos_v1.FileInfo, localPath string) (
+	file DownloadedFile, err error,
+) {
+	var r *FileReader
+	r, err = newFileReaderBuilder(source, fileInfo.GetPath()).SetContext(ctx).SetChunkSize(1024 * 1024).Build()
+	if err != nil {
+		return
+	}
+	var perm os.FileMode = os.FileMode(fileInfo.GetMode()) & os.ModePerm
+	if perm == 0 {
+		perm = 0644
+	}
+	var f *os.File
+	f, err = os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return
+	}
+	var digest hash.Hash = sha256.New()
+	// Copy up to the listed size, as the file may grow while it is copied
+	file.Size, err = io.Copy(io.MultiWriter(f, digest), io.LimitReader(r, int64(fileInfo.GetSize())))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	file.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	if nanoseconds := fileInfo.GetMtime().GetNanoseconds(); nanoseconds != 0 {
+		var mtime time.Time = time.Unix(0, nanoseconds)
+		err = os.Chtimes(localPath, mtime, mtime)
+	}
+	return
+}
+
+// WriteSHA256Sums writes the checksums of files to w in the format read by
+// sha256sum --check.
+//
+// e.g.
+//
+// 	var f *os.File
+// 	f, err = os.Create("sandbox/SHA256SUMS")
+// 	err = WriteSHA256Sums(f, files)
+func WriteSHA256Sums(w io.Writer, files []DownloadedFile) (err error) {
+	for _, file := range files {
+		_, err = fmt.Fprintf(w, "%s  %s\n", file.SHA256, file.Path)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileReaderReadAndSeek(t *testing.T) {
+	files := newFakeFiles()
+	defer files.Teardown()
+	a := files.Agent()
+	files.set("/slave/log", "0123456789")
+
+	r, err := a.NewFileReaderBuilder("/slave/log").SetChunkSize(3).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789" {
+		t.Errorf("expected 0123456789, got %s", data)
+	}
+
+	tests := []struct {
+		offset   int64
+		whence   int
+		position int64
+		expected string
+	}{
+		{-4, io.SeekEnd, 6, "6789"},
+		{2, io.SeekStart, 2, "23456789"},
+		{-2, io.SeekCurrent, 8, "89"},
+	}
+	for _, test := range tests {
+		position, err := r.Seek(test.offset, test.whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if position != test.position {
+			t.Errorf("expected position %d, got %d", test.position, position)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, data)
+		}
+	}
+
+	if _, err = r.Seek(-11, io.SeekEnd); err == nil {
+		t.Error("expected an error for a negative position")
+	}
+	if size, err := r.Size(); err != nil || size != 10 {
+		t.Errorf("expected size 10, got %d %v", size, err)
+	}
+}
+
+func TestDownloadDirectory(t *testing.T) {
+	files := newFakeFiles()
+	defer files.Teardown()
+	a := files.Agent()
+	sandbox := "/frameworks/framework-1/executors/task-1/runs/latest"
+	files.set(sandbox+"/stdout", "out\n")
+	files.set(sandbox+"/stderr", "err\n")
+	files.set(sandbox+"/data/nested/result.json", `{"ok": true}`)
+
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloaded, err := a.DownloadDirectory(context.Background(), sandbox+"/", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"stdout":                  "out\n",
+		"stderr":                  "err\n",
+		"data/nested/result.json": `{"ok": true}`,
+	}
+	if len(downloaded) != len(expected) {
+		t.Fatalf("expected %d files, got %+v", len(expected), downloaded)
+	}
+	for _, file := range downloaded {
+		content, ok := expected[file.Path]
+		if !ok {
+			t.Errorf("unexpected file %s", file.Path)
+			continue
+		}
+		local, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(content))
+		if string(local) != content || file.Size != int64(len(content)) || file.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: unexpected download %+v with content %q", file.Path, file, local)
+		}
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 || !info.ModTime().Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("%s: expected mode 0640 and the remote mtime, got %s %s", file.Path, info.Mode(), info.ModTime())
+		}
+	}
+
+	var sums bytes.Buffer
+	if err = WriteSHA256Sums(&sums, downloaded); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sums.String(), "  data/nested/result.json\n") {
+		t.Errorf("unexpected checksums %s", sums.String())
+	}
+}
+
+func TestDownloadDirectoryGrowingFile(t *testing.T) {
+	files := newFakeFiles()
+	defer files.Teardown()
+	a := files.Agent()
+	sandbox := "/frameworks/framework-1/executors/task-1/runs/latest"
+	files.set(sandbox+"/stdout", "one\n")
+	// The task writes to its log while it is copied
+	var once sync.Once
+	files.onRead = func(path string) {
+		once.Do(func() { files.appendTo(path, "more\n") })
+	}
+
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloaded, err := a.DownloadDirectory(context.Background(), sandbox, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := ioutil.ReadFile(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(downloaded) != 1 || downloaded[0].Size != 4 || string(local) != "one\n" {
+		t.Errorf("expected the listed content only, got %+v with content %q", downloaded, local)
+	}
+}
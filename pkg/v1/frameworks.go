@@ -26,6 +26,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/mesos/go-proto/mesos/v1/agent"
 	"github.com/mesos/go-proto/mesos/v1/master"
 )
@@ -45,3 +46,16 @@ func (a *Agent) GetFrameworks(ctx context.Context) (response *mesos_v1_agent.Res
 	defer httpResponse.Body.Close()
 	return
 }
+
+// Teardown tears down a running framework by shutting down all tasks,
+// executors and the scheduler of the framework. The framework is removed and
+// cannot register again with the same FrameworkID. To review what would be
+// stopped first, use PlanTeardown and ConfirmTeardown instead.
+func (m *Master) Teardown(ctx context.Context, call *mesos_v1_master.Call_Teardown) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_TEARDOWN
+	var message proto.Message = &mesos_v1_master.Call{Type: &callType, Teardown: call}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
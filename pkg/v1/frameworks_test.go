@@ -116,3 +116,20 @@ func TestAgentGetFramework(t *testing.T) {
 		t.Errorf("expected %s, got %s", frameworkName, name)
 	}
 }
+
+func TestTeardown(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	frameworkID := "framework-1"
+	call := &mesos_v1_master.Call_Teardown{
+		FrameworkId: &mesos_v1.FrameworkID{Value: &frameworkID},
+	}
+
+	err := s.Master().Teardown(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
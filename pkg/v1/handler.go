@@ -0,0 +1,134 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// EventHandler has a method for each type of event sent by the master. Use
+// Dispatch to call the methods as events arrive. Embed NopEventHandler to
+// implement only the methods you need.
+//
+// e.g.
+//
+// 	type taskLogger struct {
+// 		v1.NopEventHandler
+// 	}
+//
+// 	func (taskLogger) OnTaskUpdated(e *mesos_v1_master.Event_TaskUpdated) {
+// 		log.Println(e.GetStatus().GetTaskId().GetValue(), e.GetState())
+// 	}
+//
+// 	err := v1.Dispatch(ctx, master, taskLogger{})
+type EventHandler interface {
+	// OnSubscribed is called with the snapshot of the cluster state sent when
+	// a subscription starts.
+	OnSubscribed(e *mesos_v1_master.Event_Subscribed)
+	// OnResync is called when a Subscriber has resubscribed. State built from
+	// earlier events should be rebuilt from the OnSubscribed call that follows.
+	OnResync()
+	OnTaskAdded(e *mesos_v1_master.Event_TaskAdded)
+	OnTaskUpdated(e *mesos_v1_master.Event_TaskUpdated)
+	OnAgentAdded(e *mesos_v1_master.Event_AgentAdded)
+	OnAgentRemoved(e *mesos_v1_master.Event_AgentRemoved)
+	OnFrameworkAdded(e *mesos_v1_master.Event_FrameworkAdded)
+	OnFrameworkUpdated(e *mesos_v1_master.Event_FrameworkUpdated)
+	OnFrameworkRemoved(e *mesos_v1_master.Event_FrameworkRemoved)
+	OnHeartbeat()
+	// OnUnknown is called with events of a type this package does not know
+	// about, such as those added by newer versions of Mesos.
+	OnUnknown(e *mesos_v1_master.Event)
+}
+
+// NopEventHandler implements EventHandler with methods that do nothing. Embed
+// it in your own handler and override the methods you need.
+type NopEventHandler struct{}
+
+func (NopEventHandler) OnSubscribed(e *mesos_v1_master.Event_Subscribed)             {}
+func (NopEventHandler) OnResync()                                                    {}
+func (NopEventHandler) OnTaskAdded(e *mesos_v1_master.Event_TaskAdded)               {}
+func (NopEventHandler) OnTaskUpdated(e *mesos_v1_master.Event_TaskUpdated)           {}
+func (NopEventHandler) OnAgentAdded(e *mesos_v1_master.Event_AgentAdded)             {}
+func (NopEventHandler) OnAgentRemoved(e *mesos_v1_master.Event_AgentRemoved)         {}
+func (NopEventHandler) OnFrameworkAdded(e *mesos_v1_master.Event_FrameworkAdded)     {}
+func (NopEventHandler) OnFrameworkUpdated(e *mesos_v1_master.Event_FrameworkUpdated) {}
+func (NopEventHandler) OnFrameworkRemoved(e *mesos_v1_master.Event_FrameworkRemoved) {}
+func (NopEventHandler) OnHeartbeat()                                                 {}
+func (NopEventHandler) OnUnknown(e *mesos_v1_master.Event)                           {}
+
+// EventSource is implemented by types that send master events on an
+// EventStream, such as Master and Subscriber.
+type EventSource interface {
+	Subscribe(ctx context.Context, es EventStream) (err error)
+}
+
+// Dispatch subscribes to events from the EventSource and calls the method of
+// the EventHandler matching each event, one event at a time. It returns when
+// the EventSource does. This method blocks, so you likely want to call it in a
+// go routine.
+func Dispatch(ctx context.Context, source EventSource, handler EventHandler) (err error) {
+	var es EventStream = make(EventStream)
+	var errChan chan error = make(chan error, 1)
+	go func() {
+		errChan <- source.Subscribe(ctx, es)
+	}()
+	for {
+		select {
+		case err = <-errChan:
+			return
+		case event := <-es:
+			DispatchEvent(handler, event)
+		}
+	}
+}
+
+// DispatchEvent calls the method of the EventHandler matching the type of the
+// event.
+func DispatchEvent(handler EventHandler, event *mesos_v1_master.Event) {
+	switch event.GetType() {
+	case mesos_v1_master.Event_SUBSCRIBED:
+		handler.OnSubscribed(event.GetSubscribed())
+	case EventResync:
+		handler.OnResync()
+	case mesos_v1_master.Event_TASK_ADDED:
+		handler.OnTaskAdded(event.GetTaskAdded())
+	case mesos_v1_master.Event_TASK_UPDATED:
+		handler.OnTaskUpdated(event.GetTaskUpdated())
+	case mesos_v1_master.Event_AGENT_ADDED:
+		handler.OnAgentAdded(event.GetAgentAdded())
+	case mesos_v1_master.Event_AGENT_REMOVED:
+		handler.OnAgentRemoved(event.GetAgentRemoved())
+	case mesos_v1_master.Event_FRAMEWORK_ADDED:
+		handler.OnFrameworkAdded(event.GetFrameworkAdded())
+	case mesos_v1_master.Event_FRAMEWORK_UPDATED:
+		handler.OnFrameworkUpdated(event.GetFrameworkUpdated())
+	case mesos_v1_master.Event_FRAMEWORK_REMOVED:
+		handler.OnFrameworkRemoved(event.GetFrameworkRemoved())
+	case mesos_v1_master.Event_HEARTBEAT:
+		handler.OnHeartbeat()
+	default:
+		handler.OnUnknown(event)
+	}
+}
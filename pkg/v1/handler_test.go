@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// recordingHandler records the agents it is told about and the number of
+// heartbeats. Every other method is inherited from NopEventHandler.
+type recordingHandler struct {
+	NopEventHandler
+	removedAgents []string
+	heartbeats    int
+	unknown       int
+	done          chan struct{}
+}
+
+func (h *recordingHandler) OnAgentRemoved(e *mesos_v1_master.Event_AgentRemoved) {
+	h.removedAgents = append(h.removedAgents, e.GetAgentId().GetValue())
+}
+
+func (h *recordingHandler) OnHeartbeat() {
+	h.heartbeats++
+}
+
+func (h *recordingHandler) OnUnknown(e *mesos_v1_master.Event) {
+	h.unknown++
+	if h.done != nil {
+		close(h.done)
+	}
+}
+
+func TestDispatchEvent(t *testing.T) {
+	h := &recordingHandler{}
+	unknown := mesos_v1_master.Event_Type(1000)
+	for _, event := range []*mesos_v1_master.Event{
+		testState(),
+		agentRemovedEvent("agent-1"),
+		heartbeatEvent(),
+		agentRemovedEvent("agent-2"),
+		heartbeatEvent(),
+		&mesos_v1_master.Event{Type: &unknown},
+	} {
+		DispatchEvent(h, event)
+	}
+
+	if len(h.removedAgents) != 2 || h.removedAgents[0] != "agent-1" || h.removedAgents[1] != "agent-2" {
+		t.Errorf("expected [agent-1 agent-2], got %v", h.removedAgents)
+	}
+	if h.heartbeats != 2 {
+		t.Errorf("expected 2 heartbeats, got %d", h.heartbeats)
+	}
+	if h.unknown != 1 {
+		t.Errorf("expected 1 unknown event, got %d", h.unknown)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	unknown := mesos_v1_master.Event_Type(1000)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(
+			t, rw, subscribedEvent(), agentRemovedEvent("agent-1"), heartbeatEvent(),
+			&mesos_v1_master.Event{Type: &unknown},
+		)
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h := &recordingHandler{done: make(chan struct{})}
+	errChan := make(chan error, 1)
+	go func() { errChan <- Dispatch(ctx, m, h) }()
+
+	// The unknown event is the last one sent
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("expected %s, got %v", context.Canceled, err)
+	}
+
+	if len(h.removedAgents) != 1 || h.removedAgents[0] != "agent-1" {
+		t.Errorf("expected [agent-1], got %v", h.removedAgents)
+	}
+	if h.heartbeats != 1 {
+		t.Errorf("expected 1 heartbeat, got %d", h.heartbeats)
+	}
+}
@@ -0,0 +1,335 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// ErrSlowSubscriber is the error of a HubSubscriber with the OverflowDisconnect
+// policy that was disconnected because its buffer was full.
+var ErrSlowSubscriber error = errors.New("subscriber disconnected: buffer full")
+
+// OverflowPolicy decides what a Hub does with an event for a HubSubscriber
+// whose buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber has room for the event. The Hub,
+	// and with it every other subscriber, waits too.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered event to make room.
+	OverflowDropOldest
+	// OverflowDisconnect closes the subscriber with ErrSlowSubscriber.
+	OverflowDisconnect
+)
+
+// Hub holds a single subscription to master events and fans the events out
+// to any number of HubSubscribers, so that a slow reader does not hold up the
+// event stream. Each HubSubscriber has its own buffer, overflow policy and
+// event type filter. A HubSubscriber only receives events that arrive after
+// it is built. Create a Hub with NewHub and start it with Run.
+type Hub struct {
+	source EventSource
+
+	mu          sync.Mutex
+	subscribers map[*HubSubscriber]struct{}
+	stopped     bool
+	err         error
+}
+
+// NewHub returns a pointer to a Hub that receives events from the given
+// EventSource, such as a Master or a Subscriber.
+func NewHub(source EventSource) *Hub {
+	return &Hub{source: source, subscribers: make(map[*HubSubscriber]struct{})}
+}
+
+// Run subscribes to events from the EventSource and fans them out until the
+// EventSource returns. Every HubSubscriber is then closed with the error
+// returned by the EventSource. This method blocks, so you likely want to call
+// it in a go routine.
+func (h *Hub) Run(ctx context.Context) (err error) {
+	var es EventStream = make(EventStream)
+	var errChan chan error = make(chan error, 1)
+	go func() {
+		errChan <- h.source.Subscribe(ctx, es)
+	}()
+	for {
+		select {
+		case err = <-errChan:
+			h.stop(err)
+			return
+		case event := <-es:
+			h.broadcast(ctx, event)
+		}
+	}
+}
+
+// broadcast sends the event to each HubSubscriber according to its filter and
+// overflow policy.
+func (h *Hub) broadcast(ctx context.Context, event *mesos_v1_master.Event) {
+	h.mu.Lock()
+	var subscribers []*HubSubscriber = make([]*HubSubscriber, 0, len(h.subscribers))
+	for s := range h.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subscribers {
+		if s.closed() {
+			h.remove(s)
+			continue
+		}
+		if !s.accepts(event) {
+			continue
+		}
+		switch s.policy {
+		case OverflowBlock:
+			select {
+			case s.events <- event:
+			case <-s.done:
+				h.remove(s)
+			case <-ctx.Done():
+			}
+		case OverflowDropOldest:
+			for sent := false; !sent; {
+				select {
+				case s.events <- event:
+					sent = true
+				default:
+					select {
+					case <-s.events:
+						atomic.AddUint64(&s.dropped, 1)
+					default:
+					}
+				}
+			}
+		case OverflowDisconnect:
+			select {
+			case s.events <- event:
+			default:
+				s.close(ErrSlowSubscriber)
+				h.remove(s)
+			}
+		}
+	}
+}
+
+// add registers the HubSubscriber, or closes it right away if the Hub has
+// stopped.
+func (h *Hub) add(s *HubSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		s.close(h.err)
+		close(s.events)
+		return
+	}
+	h.subscribers[s] = struct{}{}
+}
+
+// remove unregisters the HubSubscriber and closes its channel. It is only
+// called by the go routine sending events, so that no event is sent on a
+// closed channel.
+func (h *Hub) remove(s *HubSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
+
+// stop closes every HubSubscriber with the given error.
+func (h *Hub) stop(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	h.err = err
+	for s := range h.subscribers {
+		s.close(err)
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
+
+// HubSubscriberBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct. Create a HubSubscriberBuilder with
+// NewHubSubscriberBuilder
+type HubSubscriberBuilder struct {
+	hub        *Hub
+	bufferSize int
+	policy     OverflowPolicy
+	eventTypes []mesos_v1_master.Event_Type
+}
+
+// NewHubSubscriberBuilder returns a pointer to a HubSubscriberBuilder for a
+// HubSubscriber of the given Hub.
+func NewHubSubscriberBuilder(hub *Hub) *HubSubscriberBuilder {
+	return &HubSubscriberBuilder{hub: hub, bufferSize: 100}
+}
+
+// SetBufferSize sets the number of events buffered for the HubSubscriber and
+// returns a pointer to the HubSubscriberBuilder. If SetBufferSize is not
+// called, it will be set to 100.
+//
+// e.g.
+//
+// 	var b *HubSubscriberBuilder = NewHubSubscriberBuilder(hub).SetBufferSize(1000)
+func (b *HubSubscriberBuilder) SetBufferSize(bufferSize int) *HubSubscriberBuilder {
+	b.bufferSize = bufferSize
+	return b
+}
+
+// SetOverflowPolicy sets what happens to events for the HubSubscriber when
+// its buffer is full and returns a pointer to the HubSubscriberBuilder. If
+// SetOverflowPolicy is not called, it will be set to OverflowBlock.
+//
+// e.g.
+//
+// 	var b *HubSubscriberBuilder = NewHubSubscriberBuilder(hub).SetOverflowPolicy(OverflowDropOldest)
+func (b *HubSubscriberBuilder) SetOverflowPolicy(policy OverflowPolicy) *HubSubscriberBuilder {
+	b.policy = policy
+	return b
+}
+
+// SetEventTypes limits the events sent to the HubSubscriber to the given types
+// and returns a pointer to the HubSubscriberBuilder. If SetEventTypes is not
+// called, events of every type are sent. Include EventResync and
+// mesos_v1_master.Event_SUBSCRIBED to be told about resubscriptions.
+//
+// e.g.
+//
+// 	var b *HubSubscriberBuilder = NewHubSubscriberBuilder(hub).SetEventTypes(
+// 		mesos_v1_master.Event_TASK_ADDED, mesos_v1_master.Event_TASK_UPDATED,
+// 	)
+func (b *HubSubscriberBuilder) SetEventTypes(eventTypes ...mesos_v1_master.Event_Type) *HubSubscriberBuilder {
+	b.eventTypes = eventTypes
+	return b
+}
+
+// Build returns a pointer to a constructed HubSubscriber that is registered
+// with the Hub.
+func (b *HubSubscriberBuilder) Build() (s *HubSubscriber, err error) {
+	if b.hub == nil {
+		err = errors.New("a Hub is required")
+		return
+	}
+	if b.bufferSize < 1 {
+		err = errors.New("the buffer size must be at least 1")
+		return
+	}
+	switch b.policy {
+	case OverflowBlock, OverflowDropOldest, OverflowDisconnect:
+	default:
+		err = errors.New("unknown overflow policy")
+		return
+	}
+	s = &HubSubscriber{
+		events: make(EventStream, b.bufferSize),
+		policy: b.policy,
+		done:   make(chan struct{}),
+	}
+	if len(b.eventTypes) > 0 {
+		s.eventTypes = make(map[mesos_v1_master.Event_Type]struct{}, len(b.eventTypes))
+		for _, eventType := range b.eventTypes {
+			s.eventTypes[eventType] = struct{}{}
+		}
+	}
+	b.hub.add(s)
+	return
+}
+
+// HubSubscriber receives events from a Hub. Build a HubSubscriber with a
+// HubSubscriberBuilder.
+type HubSubscriber struct {
+	events     EventStream
+	policy     OverflowPolicy
+	eventTypes map[mesos_v1_master.Event_Type]struct{}
+	dropped    uint64
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// Events returns the channel on which events are received. The channel is
+// closed when the HubSubscriber is closed, after which Err reports why.
+func (s *HubSubscriber) Events() <-chan *mesos_v1_master.Event {
+	return s.events
+}
+
+// Err returns the reason the HubSubscriber was closed by the Hub:
+// ErrSlowSubscriber or the error returned by the Hub's EventSource. It returns
+// nil while the HubSubscriber is open or if it was closed with Close.
+func (s *HubSubscriber) Err() (err error) {
+	select {
+	case <-s.done:
+		err = s.err
+	default:
+	}
+	return
+}
+
+// Dropped returns the number of events discarded by the OverflowDropOldest
+// policy.
+func (s *HubSubscriber) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops the delivery of events to the HubSubscriber. The Hub closes the
+// channel returned by Events when it next has an event to deliver or when it
+// stops.
+func (s *HubSubscriber) Close() {
+	s.close(nil)
+}
+
+func (s *HubSubscriber) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+func (s *HubSubscriber) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// accepts reports whether the event passes the event type filter.
+func (s *HubSubscriber) accepts(event *mesos_v1_master.Event) bool {
+	if s.eventTypes == nil {
+		return true
+	}
+	_, ok := s.eventTypes[event.GetType()]
+	return ok
+}
@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+var errSourceDone error = errors.New("source done")
+
+// sliceSource is an EventSource that sends its events, then returns
+// errSourceDone.
+type sliceSource []*mesos_v1_master.Event
+
+func (s sliceSource) Subscribe(ctx context.Context, es EventStream) (err error) {
+	for _, event := range s {
+		select {
+		case es <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errSourceDone
+}
+
+// receiveAll returns the events received until the channel is closed.
+func receiveAll(events <-chan *mesos_v1_master.Event) (types []mesos_v1_master.Event_Type) {
+	for event := range events {
+		types = append(types, event.GetType())
+	}
+	return
+}
+
+func TestHubFanOut(t *testing.T) {
+	h := NewHub(sliceSource{subscribedEvent(), agentRemovedEvent("agent-1"), heartbeatEvent()})
+	all, err := NewHubSubscriberBuilder(h).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := NewHubSubscriberBuilder(h).SetEventTypes(mesos_v1_master.Event_HEARTBEAT).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Run(context.Background()); err != errSourceDone {
+		t.Errorf("expected %s, got %v", errSourceDone, err)
+	}
+
+	if types := receiveAll(all.Events()); len(types) != 3 {
+		t.Errorf("expected 3 events, got %v", types)
+	}
+	if types := receiveAll(filtered.Events()); len(types) != 1 || types[0] != mesos_v1_master.Event_HEARTBEAT {
+		t.Errorf("expected [HEARTBEAT], got %v", types)
+	}
+	if err := all.Err(); err != errSourceDone {
+		t.Errorf("expected %s, got %v", errSourceDone, err)
+	}
+}
+
+func TestHubDropOldest(t *testing.T) {
+	h := NewHub(sliceSource{subscribedEvent(), agentRemovedEvent("agent-1"), heartbeatEvent()})
+	s, err := NewHubSubscriberBuilder(h).SetBufferSize(1).SetOverflowPolicy(OverflowDropOldest).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Run(context.Background())
+
+	if types := receiveAll(s.Events()); len(types) != 1 || types[0] != mesos_v1_master.Event_HEARTBEAT {
+		t.Errorf("expected [HEARTBEAT], got %v", types)
+	}
+	if s.Dropped() != 2 {
+		t.Errorf("expected 2 dropped events, got %d", s.Dropped())
+	}
+}
+
+func TestHubDisconnect(t *testing.T) {
+	h := NewHub(sliceSource{subscribedEvent(), agentRemovedEvent("agent-1"), heartbeatEvent()})
+	slow, err := NewHubSubscriberBuilder(h).SetBufferSize(1).SetOverflowPolicy(OverflowDisconnect).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := NewHubSubscriberBuilder(h).SetBufferSize(3).SetOverflowPolicy(OverflowDisconnect).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Run(context.Background())
+
+	if types := receiveAll(slow.Events()); len(types) != 1 || types[0] != mesos_v1_master.Event_SUBSCRIBED {
+		t.Errorf("expected [SUBSCRIBED], got %v", types)
+	}
+	if err := slow.Err(); err != ErrSlowSubscriber {
+		t.Errorf("expected %s, got %v", ErrSlowSubscriber, err)
+	}
+	if types := receiveAll(fast.Events()); len(types) != 3 {
+		t.Errorf("expected 3 events, got %v", types)
+	}
+	if err := fast.Err(); err != errSourceDone {
+		t.Errorf("expected %s, got %v", errSourceDone, err)
+	}
+}
+
+func TestHubSubscriberClose(t *testing.T) {
+	h := NewHub(sliceSource{subscribedEvent(), heartbeatEvent()})
+	s, err := NewHubSubscriberBuilder(h).SetBufferSize(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// A closed subscriber with the OverflowBlock policy does not hold up the Hub
+	h.Run(context.Background())
+
+	receiveAll(s.Events())
+	if err := s.Err(); err != nil {
+		t.Errorf("expected nil, got %s", err)
+	}
+}
+
+func TestHubSubscriberAfterStop(t *testing.T) {
+	h := NewHub(sliceSource{})
+	h.Run(context.Background())
+
+	s, err := NewHubSubscriberBuilder(h).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := receiveAll(s.Events()); len(types) != 0 {
+		t.Errorf("expected no events, got %v", types)
+	}
+	if err := s.Err(); err != errSourceDone {
+		t.Errorf("expected %s, got %v", errSourceDone, err)
+	}
+}
+
+func TestHubSubscriberBuild(t *testing.T) {
+	h := NewHub(sliceSource{})
+	if _, err := NewHubSubscriberBuilder(h).SetBufferSize(0).Build(); err == nil {
+		t.Error("expected an error for a buffer size of 0, got nil")
+	}
+	if _, err := NewHubSubscriberBuilder(h).SetOverflowPolicy(OverflowPolicy(42)).Build(); err == nil {
+		t.Error("expected an error for an unknown overflow policy, got nil")
+	}
+	if _, err := NewHubSubscriberBuilder(nil).Build(); err == nil {
+		t.Error("expected an error when the Hub is nil, got nil")
+	}
+}
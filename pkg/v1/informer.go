@@ -0,0 +1,417 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// Informer keeps an in-memory copy of the cluster state. It is seeded from the
+// state in the SUBSCRIBED event and kept up to date by applying the events
+// that follow, so that reading the state does not require a GET_STATE call.
+// Every resubscription replaces the copy with a fresh snapshot.
+//
+// The Informer only holds tasks that have not reached a terminal state. The
+// objects returned by the Informer are shared and must not be modified.
+// Create an Informer with NewInformer and start it with Run.
+type Informer struct {
+	subscriber *Subscriber
+
+	mu         sync.RWMutex
+	synced     bool
+	syncedChan chan struct{}
+	tasks      map[string]*mesos_v1.Task
+	agents     map[string]*mesos_v1_master.Response_GetAgents_Agent
+	frameworks map[string]*mesos_v1_master.Response_GetFrameworks_Framework
+
+	tasksByFramework index
+	tasksByAgent     index
+	agentsByHostname index
+	frameworksByRole index
+}
+
+// NewInformer returns a pointer to an Informer that receives events from the
+// given Subscriber.
+func NewInformer(subscriber *Subscriber) *Informer {
+	var i *Informer = &Informer{subscriber: subscriber, syncedChan: make(chan struct{})}
+	i.reset()
+	return i
+}
+
+// Run subscribes to events and applies them to the Informer until the context
+// is done. This method blocks, so you likely want to call it in a go routine.
+func (i *Informer) Run(ctx context.Context) (err error) {
+	var es EventStream = make(EventStream)
+	var errChan chan error = make(chan error, 1)
+	go func() {
+		errChan <- i.subscriber.Subscribe(ctx, es)
+	}()
+	for {
+		select {
+		case err = <-errChan:
+			return
+		case event := <-es:
+			i.apply(event)
+		}
+	}
+}
+
+// HasSynced reports whether the Informer holds a snapshot of the cluster
+// state. It is false until the first SUBSCRIBED event is applied and while
+// resubscribing.
+func (i *Informer) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.synced
+}
+
+// WaitForSync blocks until the Informer has applied its first snapshot of the
+// cluster state or the context is done.
+func (i *Informer) WaitForSync(ctx context.Context) (err error) {
+	select {
+	case <-i.syncedChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+// Task returns the task with the given ID launched by the given framework, or
+// nil if the Informer does not hold it.
+func (i *Informer) Task(frameworkID string, taskID string) *mesos_v1.Task {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.tasks[taskKey(frameworkID, taskID)]
+}
+
+// Tasks returns every task held by the Informer.
+func (i *Informer) Tasks() (tasks []*mesos_v1.Task) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var keys []string
+	for key := range i.tasks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tasks = append(tasks, i.tasks[key])
+	}
+	return
+}
+
+// TasksByFramework returns the tasks launched by the framework with the given
+// ID.
+func (i *Informer) TasksByFramework(frameworkID string) (tasks []*mesos_v1.Task) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, key := range i.tasksByFramework.keys(frameworkID) {
+		tasks = append(tasks, i.tasks[key])
+	}
+	return
+}
+
+// TasksByAgent returns the tasks running on the agent with the given ID.
+func (i *Informer) TasksByAgent(agentID string) (tasks []*mesos_v1.Task) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, key := range i.tasksByAgent.keys(agentID) {
+		tasks = append(tasks, i.tasks[key])
+	}
+	return
+}
+
+// Agent returns the agent with the given ID, or nil if the Informer does not
+// hold it.
+func (i *Informer) Agent(agentID string) *mesos_v1_master.Response_GetAgents_Agent {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.agents[agentID]
+}
+
+// Agents returns every agent held by the Informer.
+func (i *Informer) Agents() (agents []*mesos_v1_master.Response_GetAgents_Agent) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var keys []string
+	for key := range i.agents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		agents = append(agents, i.agents[key])
+	}
+	return
+}
+
+// AgentsByHostname returns the agents registered with the given hostname.
+func (i *Informer) AgentsByHostname(hostname string) (agents []*mesos_v1_master.Response_GetAgents_Agent) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, key := range i.agentsByHostname.keys(hostname) {
+		agents = append(agents, i.agents[key])
+	}
+	return
+}
+
+// Framework returns the framework with the given ID, or nil if the Informer
+// does not hold it.
+func (i *Informer) Framework(frameworkID string) *mesos_v1_master.Response_GetFrameworks_Framework {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.frameworks[frameworkID]
+}
+
+// Frameworks returns every framework held by the Informer.
+func (i *Informer) Frameworks() (frameworks []*mesos_v1_master.Response_GetFrameworks_Framework) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var keys []string
+	for key := range i.frameworks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		frameworks = append(frameworks, i.frameworks[key])
+	}
+	return
+}
+
+// FrameworksByRole returns the frameworks subscribed to the given role.
+func (i *Informer) FrameworksByRole(role string) (frameworks []*mesos_v1_master.Response_GetFrameworks_Framework) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, key := range i.frameworksByRole.keys(role) {
+		frameworks = append(frameworks, i.frameworks[key])
+	}
+	return
+}
+
+// apply updates the Informer with the given event.
+func (i *Informer) apply(event *mesos_v1_master.Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	switch event.GetType() {
+	case EventResync:
+		i.synced = false
+	case mesos_v1_master.Event_SUBSCRIBED:
+		i.load(event.GetSubscribed().GetGetState())
+	case mesos_v1_master.Event_TASK_ADDED:
+		i.putTask(event.GetTaskAdded().GetTask())
+	case mesos_v1_master.Event_TASK_UPDATED:
+		i.updateTask(event.GetTaskUpdated())
+	case mesos_v1_master.Event_AGENT_ADDED:
+		i.putAgent(event.GetAgentAdded().GetAgent())
+	case mesos_v1_master.Event_AGENT_REMOVED:
+		i.deleteAgent(event.GetAgentRemoved().GetAgentId().GetValue())
+	case mesos_v1_master.Event_FRAMEWORK_ADDED:
+		i.putFramework(event.GetFrameworkAdded().GetFramework())
+	case mesos_v1_master.Event_FRAMEWORK_UPDATED:
+		i.putFramework(event.GetFrameworkUpdated().GetFramework())
+	case mesos_v1_master.Event_FRAMEWORK_REMOVED:
+		i.deleteFramework(event.GetFrameworkRemoved().GetFrameworkInfo().GetId().GetValue())
+	}
+}
+
+// reset empties the Informer.
+func (i *Informer) reset() {
+	i.tasks = make(map[string]*mesos_v1.Task)
+	i.agents = make(map[string]*mesos_v1_master.Response_GetAgents_Agent)
+	i.frameworks = make(map[string]*mesos_v1_master.Response_GetFrameworks_Framework)
+	i.tasksByFramework = make(index)
+	i.tasksByAgent = make(index)
+	i.agentsByHostname = make(index)
+	i.frameworksByRole = make(index)
+}
+
+// load replaces the contents of the Informer with the given state.
+func (i *Informer) load(state *mesos_v1_master.Response_GetState) {
+	i.reset()
+	for _, tasks := range [][]*mesos_v1.Task{
+		state.GetGetTasks().GetPendingTasks(),
+		state.GetGetTasks().GetTasks(),
+		state.GetGetTasks().GetUnreachableTasks(),
+	} {
+		for _, task := range tasks {
+			i.putTask(task)
+		}
+	}
+	for _, agent := range state.GetGetAgents().GetAgents() {
+		i.putAgent(agent)
+	}
+	for _, framework := range state.GetGetFrameworks().GetFrameworks() {
+		i.putFramework(framework)
+	}
+	if !i.synced {
+		i.synced = true
+		select {
+		case <-i.syncedChan:
+		default:
+			close(i.syncedChan)
+		}
+	}
+}
+
+func (i *Informer) putTask(task *mesos_v1.Task) {
+	var frameworkID string = task.GetFrameworkId().GetValue()
+	var key string = taskKey(frameworkID, task.GetTaskId().GetValue())
+	i.deleteTask(key)
+	if isTerminal(task.GetState()) {
+		return
+	}
+	i.tasks[key] = task
+	i.tasksByFramework.add(frameworkID, key)
+	i.tasksByAgent.add(task.GetAgentId().GetValue(), key)
+}
+
+// updateTask applies a status update to a copy of the task it belongs to, so
+// that tasks already returned by the Informer are left untouched.
+func (i *Informer) updateTask(update *mesos_v1_master.Event_TaskUpdated) {
+	var key string = taskKey(update.GetFrameworkId().GetValue(), update.GetStatus().GetTaskId().GetValue())
+	var task *mesos_v1.Task
+	var ok bool
+	if task, ok = i.tasks[key]; !ok {
+		return
+	}
+	task = proto.Clone(task).(*mesos_v1.Task)
+	var state mesos_v1.TaskState = update.GetState()
+	task.State = &state
+	task.Statuses = append(task.Statuses, update.GetStatus())
+	i.putTask(task)
+}
+
+func (i *Informer) deleteTask(key string) {
+	var task *mesos_v1.Task
+	var ok bool
+	if task, ok = i.tasks[key]; !ok {
+		return
+	}
+	delete(i.tasks, key)
+	i.tasksByFramework.remove(task.GetFrameworkId().GetValue(), key)
+	i.tasksByAgent.remove(task.GetAgentId().GetValue(), key)
+}
+
+func (i *Informer) putAgent(agent *mesos_v1_master.Response_GetAgents_Agent) {
+	var key string = agent.GetAgentInfo().GetId().GetValue()
+	i.deleteAgent(key)
+	i.agents[key] = agent
+	i.agentsByHostname.add(agent.GetAgentInfo().GetHostname(), key)
+}
+
+func (i *Informer) deleteAgent(key string) {
+	var agent *mesos_v1_master.Response_GetAgents_Agent
+	var ok bool
+	if agent, ok = i.agents[key]; !ok {
+		return
+	}
+	delete(i.agents, key)
+	i.agentsByHostname.remove(agent.GetAgentInfo().GetHostname(), key)
+}
+
+func (i *Informer) putFramework(framework *mesos_v1_master.Response_GetFrameworks_Framework) {
+	var key string = framework.GetFrameworkInfo().GetId().GetValue()
+	i.deleteFramework(key)
+	i.frameworks[key] = framework
+	for _, role := range frameworkRoles(framework.GetFrameworkInfo()) {
+		i.frameworksByRole.add(role, key)
+	}
+}
+
+// deleteFramework removes the framework along with its tasks.
+func (i *Informer) deleteFramework(key string) {
+	var framework *mesos_v1_master.Response_GetFrameworks_Framework
+	var ok bool
+	if framework, ok = i.frameworks[key]; !ok {
+		return
+	}
+	delete(i.frameworks, key)
+	for _, role := range frameworkRoles(framework.GetFrameworkInfo()) {
+		i.frameworksByRole.remove(role, key)
+	}
+	for _, taskKey := range i.tasksByFramework.keys(key) {
+		i.deleteTask(taskKey)
+	}
+}
+
+// frameworkRoles returns the roles of a framework, falling back to the
+// deprecated role field for frameworks that are not multi-role capable.
+func frameworkRoles(info *mesos_v1.FrameworkInfo) []string {
+	if len(info.GetRoles()) > 0 {
+		return info.GetRoles()
+	}
+	return []string{info.GetRole()}
+}
+
+// isTerminal reports whether a task in the given state will not change state
+// again.
+func isTerminal(state mesos_v1.TaskState) bool {
+	switch state {
+	case mesos_v1.TaskState_TASK_FINISHED,
+		mesos_v1.TaskState_TASK_FAILED,
+		mesos_v1.TaskState_TASK_KILLED,
+		mesos_v1.TaskState_TASK_ERROR,
+		mesos_v1.TaskState_TASK_LOST,
+		mesos_v1.TaskState_TASK_DROPPED,
+		mesos_v1.TaskState_TASK_GONE,
+		mesos_v1.TaskState_TASK_GONE_BY_OPERATOR:
+		return true
+	}
+	return false
+}
+
+// taskKey returns the key of a task. Task IDs are only unique within a
+// framework.
+func taskKey(frameworkID string, taskID string) string {
+	return frameworkID + "/" + taskID
+}
+
+// index maps a secondary value, such as a hostname, to the set of keys it
+// appears under in a primary map.
+type index map[string]map[string]struct{}
+
+func (i index) add(value string, key string) {
+	if _, ok := i[value]; !ok {
+		i[value] = make(map[string]struct{})
+	}
+	i[value][key] = struct{}{}
+}
+
+func (i index) remove(value string, key string) {
+	delete(i[value], key)
+	if len(i[value]) == 0 {
+		delete(i, value)
+	}
+}
+
+// keys returns the keys for the given value in sorted order.
+func (i index) keys(value string) (keys []string) {
+	for key := range i[value] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
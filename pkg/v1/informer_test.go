@@ -0,0 +1,279 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func testTask(frameworkID, taskID, agentID string, state mesos_v1.TaskState) *mesos_v1.Task {
+	name := taskID
+	return &mesos_v1.Task{
+		Name:        &name,
+		TaskId:      &mesos_v1.TaskID{Value: &taskID},
+		FrameworkId: &mesos_v1.FrameworkID{Value: &frameworkID},
+		AgentId:     &mesos_v1.AgentID{Value: &agentID},
+		State:       &state,
+	}
+}
+
+func testAgent(agentID, hostname string) *mesos_v1_master.Response_GetAgents_Agent {
+	active := true
+	version := "1.5.0"
+	return &mesos_v1_master.Response_GetAgents_Agent{
+		AgentInfo: &mesos_v1.AgentInfo{
+			Id:       &mesos_v1.AgentID{Value: &agentID},
+			Hostname: &hostname,
+		},
+		Active:  &active,
+		Version: &version,
+	}
+}
+
+func testFramework(frameworkID string, roles ...string) *mesos_v1_master.Response_GetFrameworks_Framework {
+	user := "root"
+	name := frameworkID
+	active := true
+	connected := true
+	return &mesos_v1_master.Response_GetFrameworks_Framework{
+		FrameworkInfo: &mesos_v1.FrameworkInfo{
+			Id:    &mesos_v1.FrameworkID{Value: &frameworkID},
+			User:  &user,
+			Name:  &name,
+			Roles: roles,
+		},
+		Active:    &active,
+		Connected: &connected,
+	}
+}
+
+func testState() *mesos_v1_master.Event {
+	event := subscribedEvent()
+	event.Subscribed.GetState = &mesos_v1_master.Response_GetState{
+		GetTasks: &mesos_v1_master.Response_GetTasks{
+			Tasks: []*mesos_v1.Task{
+				testTask("framework-1", "task-1", "agent-1", mesos_v1.TaskState_TASK_RUNNING),
+				testTask("framework-1", "task-2", "agent-2", mesos_v1.TaskState_TASK_RUNNING),
+			},
+			PendingTasks: []*mesos_v1.Task{
+				testTask("framework-2", "task-1", "agent-1", mesos_v1.TaskState_TASK_STAGING),
+			},
+		},
+		GetAgents: &mesos_v1_master.Response_GetAgents{
+			Agents: []*mesos_v1_master.Response_GetAgents_Agent{
+				testAgent("agent-1", "host-1"),
+				testAgent("agent-2", "host-2"),
+			},
+		},
+		GetFrameworks: &mesos_v1_master.Response_GetFrameworks{
+			Frameworks: []*mesos_v1_master.Response_GetFrameworks_Framework{
+				testFramework("framework-1", "role-a", "role-b"),
+				testFramework("framework-2", "role-b"),
+			},
+		},
+	}
+	return event
+}
+
+func TestInformerLoadsSnapshot(t *testing.T) {
+	i := NewInformer(nil)
+	if i.HasSynced() {
+		t.Error("expected false, got true")
+	}
+	i.apply(testState())
+	if !i.HasSynced() {
+		t.Error("expected true, got false")
+	}
+
+	if n := len(i.Tasks()); n != 3 {
+		t.Errorf("expected 3 tasks, got %d", n)
+	}
+	if n := len(i.TasksByFramework("framework-1")); n != 2 {
+		t.Errorf("expected 2 tasks for framework-1, got %d", n)
+	}
+	if n := len(i.TasksByAgent("agent-1")); n != 2 {
+		t.Errorf("expected 2 tasks on agent-1, got %d", n)
+	}
+	agents := i.AgentsByHostname("host-2")
+	if len(agents) != 1 || agents[0].GetAgentInfo().GetId().GetValue() != "agent-2" {
+		t.Errorf("expected agent-2, got %v", agents)
+	}
+	if n := len(i.FrameworksByRole("role-a")); n != 1 {
+		t.Errorf("expected 1 framework for role-a, got %d", n)
+	}
+	if n := len(i.FrameworksByRole("role-b")); n != 2 {
+		t.Errorf("expected 2 frameworks for role-b, got %d", n)
+	}
+}
+
+func TestInformerAppliesEvents(t *testing.T) {
+	i := NewInformer(nil)
+	i.apply(testState())
+
+	// A new task on a new agent
+	taskAdded := mesos_v1_master.Event_TASK_ADDED
+	i.apply(&mesos_v1_master.Event{
+		Type: &taskAdded,
+		TaskAdded: &mesos_v1_master.Event_TaskAdded{
+			Task: testTask("framework-2", "task-2", "agent-3", mesos_v1.TaskState_TASK_STAGING),
+		},
+	})
+	agentAdded := mesos_v1_master.Event_AGENT_ADDED
+	i.apply(&mesos_v1_master.Event{
+		Type:       &agentAdded,
+		AgentAdded: &mesos_v1_master.Event_AgentAdded{Agent: testAgent("agent-3", "host-3")},
+	})
+	if n := len(i.TasksByAgent("agent-3")); n != 1 {
+		t.Errorf("expected 1 task on agent-3, got %d", n)
+	}
+	if n := len(i.AgentsByHostname("host-3")); n != 1 {
+		t.Errorf("expected 1 agent for host-3, got %d", n)
+	}
+
+	// A task starts running, another finishes
+	before := i.Task("framework-2", "task-2")
+	for _, update := range []struct {
+		frameworkID string
+		taskID      string
+		state       mesos_v1.TaskState
+	}{
+		{"framework-2", "task-2", mesos_v1.TaskState_TASK_RUNNING},
+		{"framework-1", "task-2", mesos_v1.TaskState_TASK_FINISHED},
+	} {
+		taskUpdated := mesos_v1_master.Event_TASK_UPDATED
+		frameworkID, taskID, state := update.frameworkID, update.taskID, update.state
+		i.apply(&mesos_v1_master.Event{
+			Type: &taskUpdated,
+			TaskUpdated: &mesos_v1_master.Event_TaskUpdated{
+				FrameworkId: &mesos_v1.FrameworkID{Value: &frameworkID},
+				Status: &mesos_v1.TaskStatus{
+					TaskId: &mesos_v1.TaskID{Value: &taskID},
+					State:  &state,
+				},
+				State: &state,
+			},
+		})
+	}
+	if state := i.Task("framework-2", "task-2").GetState(); state != mesos_v1.TaskState_TASK_RUNNING {
+		t.Errorf("expected TASK_RUNNING, got %s", state)
+	}
+	if state := before.GetState(); state != mesos_v1.TaskState_TASK_STAGING {
+		t.Errorf("expected a returned task to be left untouched, got %s", state)
+	}
+	if task := i.Task("framework-1", "task-2"); task != nil {
+		t.Errorf("expected a finished task to be removed, got %v", task)
+	}
+	if n := len(i.TasksByAgent("agent-2")); n != 0 {
+		t.Errorf("expected 0 tasks on agent-2, got %d", n)
+	}
+
+	// An agent goes away
+	agentRemoved := agentRemovedEvent("agent-2")
+	i.apply(agentRemoved)
+	if agent := i.Agent("agent-2"); agent != nil {
+		t.Errorf("expected agent-2 to be removed, got %v", agent)
+	}
+	if n := len(i.AgentsByHostname("host-2")); n != 0 {
+		t.Errorf("expected 0 agents for host-2, got %d", n)
+	}
+
+	// A framework moves to a new role, another is removed with its tasks
+	frameworkUpdated := mesos_v1_master.Event_FRAMEWORK_UPDATED
+	i.apply(&mesos_v1_master.Event{
+		Type: &frameworkUpdated,
+		FrameworkUpdated: &mesos_v1_master.Event_FrameworkUpdated{
+			Framework: testFramework("framework-1", "role-c"),
+		},
+	})
+	if n := len(i.FrameworksByRole("role-a")); n != 0 {
+		t.Errorf("expected 0 frameworks for role-a, got %d", n)
+	}
+	if n := len(i.FrameworksByRole("role-c")); n != 1 {
+		t.Errorf("expected 1 framework for role-c, got %d", n)
+	}
+	frameworkRemoved := mesos_v1_master.Event_FRAMEWORK_REMOVED
+	i.apply(&mesos_v1_master.Event{
+		Type: &frameworkRemoved,
+		FrameworkRemoved: &mesos_v1_master.Event_FrameworkRemoved{
+			FrameworkInfo: testFramework("framework-2").GetFrameworkInfo(),
+		},
+	})
+	if framework := i.Framework("framework-2"); framework != nil {
+		t.Errorf("expected framework-2 to be removed, got %v", framework)
+	}
+	if n := len(i.TasksByFramework("framework-2")); n != 0 {
+		t.Errorf("expected 0 tasks for framework-2, got %d", n)
+	}
+	if n := len(i.Frameworks()); n != 1 {
+		t.Errorf("expected 1 framework, got %d", n)
+	}
+}
+
+func TestInformerResync(t *testing.T) {
+	i := NewInformer(nil)
+	i.apply(testState())
+
+	resync := EventResync
+	i.apply(&mesos_v1_master.Event{Type: &resync})
+	if i.HasSynced() {
+		t.Error("expected false, got true")
+	}
+
+	// The new snapshot replaces everything held before
+	event := subscribedEvent()
+	event.Subscribed.GetState = &mesos_v1_master.Response_GetState{
+		GetAgents: &mesos_v1_master.Response_GetAgents{
+			Agents: []*mesos_v1_master.Response_GetAgents_Agent{testAgent("agent-9", "host-9")},
+		},
+	}
+	i.apply(event)
+	if !i.HasSynced() {
+		t.Error("expected true, got false")
+	}
+	if n := len(i.Tasks()); n != 0 {
+		t.Errorf("expected 0 tasks, got %d", n)
+	}
+	if agents := i.Agents(); len(agents) != 1 || agents[0].GetAgentInfo().GetHostname() != "host-9" {
+		t.Errorf("expected host-9, got %v", agents)
+	}
+}
+
+func TestInformerRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(t, rw, testState())
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := NewInformer(s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() { errChan <- i.Run(ctx) }()
+
+	if err := i.WaitForSync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(i.Tasks()); n != 3 {
+		t.Errorf("expected 3 tasks, got %d", n)
+	}
+
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("expected %s, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// Invoker sends a call and decodes the response into response. The call is a
+// *mesos_v1_master.Call or *mesos_v1_agent.Call and the response the matching
+// *mesos_v1_master.Response or *mesos_v1_agent.Response. The response is nil
+// for calls without one, and for streaming calls, such as SUBSCRIBE or
+// ATTACH_CONTAINER_OUTPUT, whose stream is read from the body of the returned
+// *http.Response.
+type Invoker func(ctx context.Context, call proto.Message, response proto.Message) (
+	httpResponse *http.Response, err error,
+)
+
+// Interceptor runs around every call made by a Master or Agent, including the
+// calls that start a stream. It receives the decoded call and invokes the next
+// interceptor, or the call itself, with invoker. After invoker returns, the
+// decoded response or the error is available.
+//
+// An Interceptor may modify the call before invoking it, add request headers
+// with WithRequestHeader, or short-circuit the call by filling in response and
+// returning without invoking it. A short-circuited call that returns no
+// *http.Response gets one with an empty body.
+//
+// e.g.
+//
+// 	var logCalls Interceptor = func(
+// 		ctx context.Context, call proto.Message, response proto.Message, invoker Invoker,
+// 	) (httpResponse *http.Response, err error) {
+// 		httpResponse, err = invoker(ctx, call, response)
+// 		log.Printf("call: %s err: %v", call, err)
+// 		return
+// 	}
+type Interceptor func(ctx context.Context, call proto.Message, response proto.Message, invoker Invoker) (
+	httpResponse *http.Response, err error,
+)
+
+// chainInterceptors returns an Invoker that runs the interceptors around
+// invoker. The first interceptor is the outermost.
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		var interceptor Interceptor = interceptors[i]
+		var next Invoker = invoker
+		invoker = func(ctx context.Context, call proto.Message, response proto.Message) (
+			httpResponse *http.Response, err error,
+		) {
+			httpResponse, err = interceptor(ctx, call, response, next)
+			if httpResponse == nil && err == nil {
+				httpResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+				}
+			}
+			return
+		}
+	}
+	return invoker
+}
+
+// requestHeaderKey is the context key of the headers added with
+// WithRequestHeader.
+type requestHeaderKey struct{}
+
+// WithRequestHeader returns a copy of ctx that adds the header key with the
+// given value to the HTTP requests of calls made with it. Use it from an
+// Interceptor to inject headers, such as an audit or tracing header.
+//
+// e.g.
+//
+// 	httpResponse, err = invoker(WithRequestHeader(ctx, "X-Request-Id", id), call, response)
+func WithRequestHeader(ctx context.Context, key string, value string) context.Context {
+	var header http.Header = http.Header{}
+	if parent, ok := ctx.Value(requestHeaderKey{}).(http.Header); ok {
+		header = parent.Clone()
+	}
+	header.Add(key, value)
+	return context.WithValue(ctx, requestHeaderKey{}, header)
+}
+
+// requestHeader returns the headers added to ctx with WithRequestHeader.
+func requestHeader(ctx context.Context) http.Header {
+	var header http.Header
+	header, _ = ctx.Value(requestHeaderKey{}).(http.Header)
+	return header
+}
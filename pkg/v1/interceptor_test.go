@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func TestInterceptorsOrderAndObserve(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+	s.SetOutput(healthyOutput(t)).Handle()
+
+	var order []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call proto.Message, response proto.Message, invoker Invoker) (
+			httpResponse *http.Response, err error,
+		) {
+			order = append(order, name+" "+call.(*mesos_v1_master.Call).GetType().String())
+			httpResponse, err = invoker(ctx, call, response)
+			order = append(order, name+" healthy="+proto.CompactTextString(response.(*mesos_v1_master.Response).GetGetHealth()))
+			return
+		}
+	}
+	m, err := NewMasterBuilder(s.httpServer.URL).
+		SetHTTPClient(s.httpClient).
+		SetInterceptors(record("outer"), record("inner")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetHealth(s.Ctx()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"outer GET_HEALTH", "inner GET_HEALTH", "inner healthy=healthy:true ", "outer healthy=healthy:true "}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %q, got %q", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], order[i])
+		}
+	}
+}
+
+func TestInterceptorModifiesCallAndInjectsHeader(t *testing.T) {
+	var received mesos_v1_master.Call
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		header = req.Header.Get("X-Audit-User")
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := proto.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+		rw.Write(healthyOutput(t))
+	}))
+	defer server.Close()
+
+	rewrite := func(ctx context.Context, call proto.Message, response proto.Message, invoker Invoker) (
+		*http.Response, error,
+	) {
+		callType := mesos_v1_master.Call_GET_HEALTH
+		call.(*mesos_v1_master.Call).Type = &callType
+		return invoker(WithRequestHeader(ctx, "X-Audit-User", "operator"), call, response)
+	}
+	m, err := NewMasterBuilder(server.URL).SetInterceptors(rewrite).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetVersion(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if received.GetType() != mesos_v1_master.Call_GET_HEALTH {
+		t.Errorf("expected GET_HEALTH, got %s", received.GetType())
+	}
+	if header != "operator" {
+		t.Errorf("expected operator, got %q", header)
+	}
+}
+
+func TestInterceptorShortCircuits(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	cached := func(ctx context.Context, call proto.Message, response proto.Message, invoker Invoker) (
+		*http.Response, error,
+	) {
+		healthy := true
+		response.(*mesos_v1_master.Response).GetHealth = &mesos_v1_master.Response_GetHealth{Healthy: &healthy}
+		return nil, nil
+	}
+	m, err := NewMasterBuilder(server.URL).SetInterceptors(cached).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Errorf("expected no requests, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestInterceptorRunsAroundStreamingCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(t, rw, subscribedEvent())
+	}))
+	defer server.Close()
+
+	var streamed bool
+	observe := func(ctx context.Context, call proto.Message, response proto.Message, invoker Invoker) (
+		httpResponse *http.Response, err error,
+	) {
+		httpResponse, err = invoker(ctx, call, response)
+		streamed = call.(*mesos_v1_master.Call).GetType() == mesos_v1_master.Call_SUBSCRIBE &&
+			response == nil && httpResponse != nil
+		return
+	}
+	m, err := NewMasterBuilder(server.URL).SetInterceptors(observe).SetMaxMissedHeartbeats(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := make(EventStream, 1)
+	m.Subscribe(context.Background(), es)
+	if !streamed {
+		t.Error("expected the interceptor to see the SUBSCRIBE call and its stream")
+	}
+	if len(es) != 1 {
+		t.Errorf("expected 1 event, got %d", len(es))
+	}
+}
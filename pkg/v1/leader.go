@@ -0,0 +1,80 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"net/url"
+	"sync"
+)
+
+// maxRedirects is the number of redirects doProto follows for a single request
+// before giving up. One redirect is enough to reach the leading master; the rest
+// absorb a leadership change happening while the request is in flight.
+const maxRedirects int = 3
+
+// leader tracks the leading master among the configured server URLs. A single
+// server URL, such as the one given to an Agent, is always its own leader.
+//
+// The zero value is not usable; create a leader with newLeader.
+type leader struct {
+	mu         sync.Mutex
+	serverURLs []*url.URL
+	current    *url.URL
+	next       int
+}
+
+// newLeader returns a pointer to a leader that starts with the first of the
+// given server URLs.
+func newLeader(serverURLs []*url.URL) *leader {
+	return &leader{serverURLs: serverURLs}
+}
+
+// get returns the cached leader. If no leader is cached, the next server URL
+// is tried in round robin order. A non-leading master will redirect to the
+// leader, at which point set is called with the leader's URL.
+func (l *leader) get() *url.URL {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.current == nil {
+		l.current = l.serverURLs[l.next%len(l.serverURLs)]
+		l.next++
+	}
+	return l.current
+}
+
+// set caches the given URL as the leader.
+func (l *leader) set(u *url.URL) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.current = u
+}
+
+// forget clears the cached leader if it is the given URL, so that the next
+// call to get looks for the leader again. A URL that is no longer cached is
+// ignored, since another request has already moved on from it.
+func (l *leader) forget(u *url.URL) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.current == u {
+		l.current = nil
+	}
+}
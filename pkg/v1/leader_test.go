@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newRedirectServer returns a server that acts as a non-leading master,
+// redirecting every request to location. hits counts the requests it received.
+func newRedirectServer(location string, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		rw.Header().Set("Location", location)
+		rw.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+}
+
+func healthyOutput(t *testing.T) []byte {
+	responseType := mesos_v1_master.Response_GET_HEALTH
+	healthy := true
+	output, err := proto.Marshal(&mesos_v1_master.Response{
+		Type:      &responseType,
+		GetHealth: &mesos_v1_master.Response_GetHealth{Healthy: &healthy},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestMasterFollowsRedirectToLeader(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+	s.SetOutput(healthyOutput(t)).Handle()
+
+	var hits int32
+	follower := newRedirectServer(s.httpServer.URL+"/api/v1", &hits)
+	defer follower.Close()
+
+	m, err := NewMasterBuilder(follower.URL, s.httpServer.URL).SetHTTPClient(s.httpClient).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		data, err := m.GetHealth(s.Ctx())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !data.GetGetHealth().GetHealthy() {
+			t.Error("expected true, got false")
+		}
+	}
+
+	// The leader is cached after the first redirect
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("expected 1 request to the non-leading master, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestMasterFollowsSchemeRelativeRedirect(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+	s.SetOutput(healthyOutput(t)).Handle()
+
+	// Mesos redirects to //HOSTNAME:PORT/api/v1
+	var hits int32
+	location := strings.TrimPrefix(s.httpServer.URL, "http:") + "/api/v1"
+	follower := newRedirectServer(location, &hits)
+	defer follower.Close()
+
+	m, err := NewMasterBuilder(follower.URL).SetHTTPClient(s.httpClient).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := m.GetHealth(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+}
+
+func TestMasterRediscoversLeaderOnConnectionFailure(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+	s.SetOutput(healthyOutput(t)).Handle()
+
+	// A master that has gone away
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	m, err := NewMasterBuilder(gone.URL, s.httpServer.URL).SetHTTPClient(s.httpClient).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := m.GetHealth(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+}
+
+func TestMasterRedirectLoop(t *testing.T) {
+	var hits int32
+	var follower *httptest.Server
+	follower = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		rw.Header().Set("Location", follower.URL+"/api/v1")
+		rw.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	defer follower.Close()
+
+	m, err := NewMasterBuilder(follower.URL).SetHTTPClient(follower.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.GetHealth(context.Background())
+	if _, ok := err.(HTTPError); !ok {
+		t.Errorf("expected HTTPError, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != int32(maxRedirects+1) {
+		t.Errorf("expected %d requests, got %d", maxRedirects+1, atomic.LoadInt32(&hits))
+	}
+}
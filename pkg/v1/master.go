@@ -23,13 +23,17 @@
 package v1
 
 import (
-	"bytes"
 	"context"
-	"io"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
 	"github.com/mesos/go-proto/mesos/v1/master"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MasterBuilder is a builder that takes some manditory parameters and
@@ -38,12 +42,25 @@ import (
 // NewMasterBuilder
 type MasterBuilder struct {
 	*clientBuilder
+	maxMissedHeartbeats *int
 }
 
 // NewMasterBuilder returns a pointer to an MasterBuilder. The serverURL is the
-// base URL of the mesos_v1_agent, including the SCHEMA://FQDN_OR_IP:PORT
-func NewMasterBuilder(serverURL string) *MasterBuilder {
-	return &MasterBuilder{clientBuilder: newClientBuilder(serverURL)}
+// base URL of the mesos_v1_master, including the SCHEMA://FQDN_OR_IP:PORT
+//
+// When the masters run as an HA set, pass the URL of every master. Requests
+// sent to a non-leading master follow its redirect to the leading master,
+// which is then cached and used for subsequent requests. If the leading master
+// can no longer be reached, the next attempt looks for the leader again,
+// starting with the next of the given URLs.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder(
+// 		"http://10.0.0.1:5050", "http://10.0.0.2:5050", "http://10.0.0.3:5050",
+// 	)
+func NewMasterBuilder(serverURL string, serverURLs ...string) *MasterBuilder {
+	return &MasterBuilder{clientBuilder: newClientBuilder(append([]string{serverURL}, serverURLs...)...)}
 }
 
 // SetHTTPClient sets the *http.Client for the Master and returns a pointer
@@ -58,27 +75,210 @@ func (b *MasterBuilder) SetHTTPClient(httpclient *http.Client) *MasterBuilder {
 	return b
 }
 
-// SetMaxRetries sets maxRetries for the Agent and returns a pointer to an
+// SetEncoding sets the wire encoding of calls, responses and streamed records
+// and returns a pointer to the MasterBuilder. If SetEncoding is not called, it
+// will be set to EncodingProtobuf.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetEncoding(EncodingJSON)
+func (b *MasterBuilder) SetEncoding(encoding Encoding) *MasterBuilder {
+	b.clientBuilder.setEncoding(encoding)
+	return b
+}
+
+// SetBasicAuth sets the principal and secret sent with each request using
+// HTTP Basic authentication and returns a pointer to the MasterBuilder. Use it
+// when the master is started with --authenticate_http_readwrite or
+// --authenticate_http_readonly. SetBasicAuth cannot be combined with
+// SetCredentialProvider.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetBasicAuth("principal", "secret")
+func (b *MasterBuilder) SetBasicAuth(principal string, secret string) *MasterBuilder {
+	b.clientBuilder.setBasicAuth(principal, secret)
+	return b
+}
+
+// SetCredentialProvider sets the CredentialProvider that supplies the bearer
+// token sent with each request and returns a pointer to the MasterBuilder. A
+// request rejected with a 401 is sent once more after the token is refreshed.
+// SetCredentialProvider cannot be combined with SetBasicAuth.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetCredentialProvider(myProvider)
+func (b *MasterBuilder) SetCredentialProvider(credentialProvider CredentialProvider) *MasterBuilder {
+	b.clientBuilder.setCredentialProvider(credentialProvider)
+	return b
+}
+
+// SetCACertFile sets the path of the PEM encoded CA bundle used to verify the
+// master's certificate and returns a pointer to the MasterBuilder. If
+// SetCACertFile is not called, the host's root CA set is used. The file is
+// reloaded when it changes on disk.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetCACertFile("/etc/mesos/ca.pem")
+func (b *MasterBuilder) SetCACertFile(caFile string) *MasterBuilder {
+	b.clientBuilder.setCACertFile(caFile)
+	return b
+}
+
+// SetClientCertFiles sets the paths of the PEM encoded certificate and key
+// presented to the master for mutual TLS and returns a pointer to the
+// MasterBuilder. The files are reloaded when they change on disk.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetClientCertFiles("/etc/mesos/client.pem", "/etc/mesos/client-key.pem")
+func (b *MasterBuilder) SetClientCertFiles(certFile string, keyFile string) *MasterBuilder {
+	b.clientBuilder.setClientCertFiles(certFile, keyFile)
+	return b
+}
+
+// SetServerName sets the name used to verify the master's certificate and
+// returns a pointer to the MasterBuilder. If SetServerName is not called, the
+// host of the server URL is used.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://10.0.0.1:5050").SetServerName("master.mesos")
+func (b *MasterBuilder) SetServerName(serverName string) *MasterBuilder {
+	b.clientBuilder.setServerName(serverName)
+	return b
+}
+
+// SetRetryPolicy sets the RetryPolicy that decides whether a failed call is
+// sent again and returns a pointer to the MasterBuilder. If SetRetryPolicy is not
+// called, the policy described by SetMaxRetries is used.
+//
+// e.g.
+//
+// 	var backoff RetryPolicy
+// 	backoff, err = NewExponentialBackoffBuilder().SetMaxRetries(5).Build()
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetRetryPolicy(
+// 		IdempotentOnly(WithMaxElapsedTime(backoff, 30*time.Second)),
+// 	)
+func (b *MasterBuilder) SetRetryPolicy(retryPolicy RetryPolicy) *MasterBuilder {
+	b.clientBuilder.setRetryPolicy(retryPolicy)
+	return b
+}
+
+// SetAttemptHook sets a function that is called with the outcome of every
+// attempt of every call, successful or not, and returns a pointer to the
+// MasterBuilder. Use it to log or count retries. The hook is called on the
+// goroutine making the call and must not block.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetAttemptHook(func(attempt Attempt) {
+// 		if attempt.Err != nil {
+// 			log.Printf("%s attempt %d failed: %s", attempt.CallType, attempt.Number, attempt.Err)
+// 		}
+// 	})
+func (b *MasterBuilder) SetAttemptHook(attemptHook func(attempt Attempt)) *MasterBuilder {
+	b.clientBuilder.setAttemptHook(attemptHook)
+	return b
+}
+
+// SetInterceptors sets the Interceptors that run around every call and
+// returns a pointer to the MasterBuilder. The first Interceptor is the
+// outermost. If SetInterceptors is not called, calls are sent as they are.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetInterceptors(logCalls, auditCalls)
+func (b *MasterBuilder) SetInterceptors(interceptors ...Interceptor) *MasterBuilder {
+	b.clientBuilder.setInterceptors(interceptors...)
+	return b
+}
+
+// SetTracerProvider enables OpenTelemetry tracing with the given
+// trace.TracerProvider and returns a pointer to the MasterBuilder. Each call
+// produces a client span named after its Call_Type, e.g. GET_HEALTH, with
+// attributes for the endpoint, the retry count, the HTTP status and the
+// response size, and the trace context is propagated through the request
+// headers. If SetTracerProvider is not called, calls are not traced.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetTracerProvider(otel.GetTracerProvider())
+func (b *MasterBuilder) SetTracerProvider(tracerProvider trace.TracerProvider) *MasterBuilder {
+	b.clientBuilder.setTracerProvider(tracerProvider)
+	return b
+}
+
+// SetPropagator sets the propagation.TextMapPropagator that writes the trace
+// context into the request headers and returns a pointer to the MasterBuilder.
+// If SetPropagator is not called, the global propagator returned by
+// otel.GetTextMapPropagator is used.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetPropagator(propagation.TraceContext{})
+func (b *MasterBuilder) SetPropagator(propagator propagation.TextMapPropagator) *MasterBuilder {
+	b.clientBuilder.setPropagator(propagator)
+	return b
+}
+
+// SetClientMetrics sets the ClientMetrics that record the calls made by the
+// Master and returns a pointer to the MasterBuilder. If SetClientMetrics is not
+// called, no metrics are recorded.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetClientMetrics(metrics)
+func (b *MasterBuilder) SetClientMetrics(metrics *ClientMetrics) *MasterBuilder {
+	b.clientBuilder.setClientMetrics(metrics)
+	return b
+}
+
+// SetMaxRetries sets maxRetries for the Master and returns a pointer to an
 // MasterBuilder. If SetMaxRetries is not called, it will be set to 10.
 // Each HTTP request will retry up to the provided value upon failure.
-// Binary exponential backoff is implemented as specified by RFC2616
+// Binary exponential backoff is implemented as specified by RFC2616. Only calls
+// that are safe to send more than once are retried, see IdempotentOnly.
+// SetMaxRetries has no effect if SetRetryPolicy is called.
 //
 // e.g.
 //
-// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5051").SetMaxRetries(5)
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetMaxRetries(5)
 func (b *MasterBuilder) SetMaxRetries(maxRetries int) *MasterBuilder {
 	b.clientBuilder.setMaxRetries(maxRetries)
 	return b
 }
 
+// SetMaxMissedHeartbeats sets the number of consecutive HEARTBEAT events that
+// may be missed on an event stream before Subscribe gives up with an
+// ErrHeartbeatTimeout, and returns a pointer to the MasterBuilder. The
+// heartbeat interval is the one announced by the master in the SUBSCRIBED
+// event. If SetMaxMissedHeartbeats is not called, it will be set to 3. Set it
+// to 0 to wait on the stream indefinitely.
+//
+// e.g.
+//
+// 	var b *MasterBuilder = NewMasterBuilder("https://127.0.0.1:5050").SetMaxMissedHeartbeats(5)
+func (b *MasterBuilder) SetMaxMissedHeartbeats(maxMissedHeartbeats int) *MasterBuilder {
+	b.maxMissedHeartbeats = &maxMissedHeartbeats
+	return b
+}
+
 // Build returns a pointer to a constructed Master.
 func (b *MasterBuilder) Build() (m *Master, err error) {
 	var client *client
+	b.clientBuilder.client.target = targetMaster
 	client, err = b.clientBuilder.build()
 	if err != nil {
 		return
 	}
-	m = &Master{client: client}
+	// Set maxMissedHeartbeats if not set
+	if b.maxMissedHeartbeats == nil {
+		b.SetMaxMissedHeartbeats(3)
+	}
+	m = &Master{client: client, maxMissedHeartbeats: *b.maxMissedHeartbeats}
 	return
 }
 
@@ -86,23 +286,39 @@ func (b *MasterBuilder) Build() (m *Master, err error) {
 // Mesos Operator Agent HTTP API. Build an Master with an MasterBuilder.
 type Master struct {
 	*client
+	maxMissedHeartbeats int
 }
 
-// sendSimpleCall configures a simple mesos_v1_master.Call, marshalls it into binary format,
+// NewAgentBuilder returns a pointer to an AgentBuilder for the given agent,
+// as found in the responses and events of the master. The AgentBuilder
+// inherits the HTTP client, encoding, retries, interceptors, tracing, metrics,
+// credentials and TLS settings of the Master, except for the TLS server name,
+// and the scheme of its server URL.
+//
+// e.g.
+//
+// 	var a *Agent
+// 	a, err = m.NewAgentBuilder(agent.GetAgentInfo()).Build()
+func (m *Master) NewAgentBuilder(agentInfo *mesos_v1.AgentInfo) *AgentBuilder {
+	var port int32 = agentInfo.GetPort()
+	if port == 0 {
+		port = 5051
+	}
+	var serverURL string = fmt.Sprintf(
+		"%s://%s", m.baseURL.Scheme, net.JoinHostPort(agentInfo.GetHostname(), strconv.Itoa(int(port))),
+	)
+	return &AgentBuilder{clientBuilder: m.client.inherit(serverURL)}
+}
+
+// sendSimpleCall configures a simple mesos_v1_master.Call, marshalls it into the configured encoding,
 // and sends it over HTTP to the configured mesos_v1_master. These calls don't need
 // additional configuration other than the mesos_v1_master.Call_Type
 func (m *Master) sendSimpleCall(ctx context.Context, callType mesos_v1_master.Call_Type) (
 	response *mesos_v1_master.Response, httpResponse *http.Response, err error,
 ) {
 	var callMsg proto.Message = &mesos_v1_master.Call{Type: &callType}
-	var b []byte
-	b, err = proto.Marshal(callMsg)
-	if err != nil {
-		return
-	}
-	var buf io.Reader = bytes.NewBuffer(b)
 	response = &mesos_v1_master.Response{}
-	httpResponse, err = m.client.doProtoWrapper(ctx, buf, response)
+	httpResponse, err = m.client.makeCall(ctx, callMsg, response)
 	return
 }
 
@@ -0,0 +1,451 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// ErrMetricsReset is returned by MetricsSnapshot.Diff when the master or agent
+// restarted between the two snapshots, so that its counters started over.
+var ErrMetricsReset = errors.New("metrics were reset between snapshots")
+
+// MetricsSnapshot holds the metrics of a master or agent at a point in time.
+// Use the typed getters for well-known metrics, Value for any metric and Glob
+// to look up families of metrics, such as the per-framework metrics. The
+// getters return the zero value if the metric is not in the snapshot. Create a
+// MetricsSnapshot with NewMetricsSnapshot, Master.GetMetricsSnapshot or
+// Agent.GetMetricsSnapshot.
+type MetricsSnapshot struct {
+	// Time is when the snapshot was taken.
+	Time   time.Time
+	values map[string]float64
+}
+
+// NewMetricsSnapshot returns a pointer to a MetricsSnapshot of metrics taken at
+// the given time.
+//
+// e.g.
+//
+// 	var response *mesos_v1_master.Response
+// 	response, err = m.GetMetrics(ctx)
+// 	var snapshot *MetricsSnapshot = NewMetricsSnapshot(response.GetGetMetrics().GetMetrics(), time.Now())
+func NewMetricsSnapshot(metrics []*mesos_v1.Metric, taken time.Time) *MetricsSnapshot {
+	var s *MetricsSnapshot = &MetricsSnapshot{Time: taken, values: make(map[string]float64, len(metrics))}
+	for _, metric := range metrics {
+		s.values[metric.GetName()] = metric.GetValue()
+	}
+	return s
+}
+
+// GetMetricsSnapshot retrieves the metrics of the master as a MetricsSnapshot.
+func (m *Master) GetMetricsSnapshot(ctx context.Context) (snapshot *MetricsSnapshot, err error) {
+	var taken time.Time = time.Now()
+	var response *mesos_v1_master.Response
+	response, err = m.GetMetrics(ctx)
+	if err != nil {
+		return
+	}
+	snapshot = NewMetricsSnapshot(response.GetGetMetrics().GetMetrics(), taken)
+	return
+}
+
+// GetMetricsSnapshot retrieves the metrics of the agent as a MetricsSnapshot.
+func (a *Agent) GetMetricsSnapshot(ctx context.Context) (snapshot *MetricsSnapshot, err error) {
+	var taken time.Time = time.Now()
+	var response *mesos_v1_agent.Response
+	response, err = a.GetMetrics(ctx)
+	if err != nil {
+		return
+	}
+	snapshot = NewMetricsSnapshot(response.GetGetMetrics().GetMetrics(), taken)
+	return
+}
+
+// Value returns the value of the metric with the given name and whether it is
+// in the snapshot.
+func (s *MetricsSnapshot) Value(name string) (value float64, ok bool) {
+	value, ok = s.values[name]
+	return
+}
+
+// Names returns the sorted names of the metrics in the snapshot.
+func (s *MetricsSnapshot) Names() (names []string) {
+	names = make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Glob returns the metrics whose names match pattern, keyed by name. The
+// pattern syntax is that of path.Match, so * does not match a /.
+//
+// e.g.
+//
+// 	// The messages received from each framework
+// 	var received map[string]float64
+// 	received, err = snapshot.Glob("master/frameworks/*/*/messages_received")
+func (s *MetricsSnapshot) Glob(pattern string) (values map[string]float64, err error) {
+	// Report a malformed pattern even if the snapshot is empty
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	values = map[string]float64{}
+	for name, value := range s.values {
+		var matched bool
+		matched, _ = path.Match(pattern, name)
+		if matched {
+			values[name] = value
+		}
+	}
+	return
+}
+
+// value returns the value of the metric or 0.
+func (s *MetricsSnapshot) value(name string) float64 {
+	return s.values[name]
+}
+
+// count returns the value of a metric that counts something.
+func (s *MetricsSnapshot) count(name string) uint64 {
+	return uint64(s.values[name])
+}
+
+// seconds returns the value of a metric in seconds as a time.Duration.
+func (s *MetricsSnapshot) seconds(name string) time.Duration {
+	return time.Duration(s.values[name] * float64(time.Second))
+}
+
+// MasterElected reports whether the master is the elected master.
+func (s *MetricsSnapshot) MasterElected() bool {
+	return s.value("master/elected") == 1
+}
+
+// MasterUptime returns the uptime of the master.
+func (s *MetricsSnapshot) MasterUptime() time.Duration {
+	return s.seconds("master/uptime_secs")
+}
+
+// MasterCPUsPercent returns the fraction of the CPUs of the cluster in use.
+func (s *MetricsSnapshot) MasterCPUsPercent() float64 {
+	return s.value("master/cpus_percent")
+}
+
+// MasterCPUsTotal returns the number of CPUs in the cluster.
+func (s *MetricsSnapshot) MasterCPUsTotal() float64 {
+	return s.value("master/cpus_total")
+}
+
+// MasterCPUsUsed returns the number of CPUs of the cluster in use.
+func (s *MetricsSnapshot) MasterCPUsUsed() float64 {
+	return s.value("master/cpus_used")
+}
+
+// MasterMemPercent returns the fraction of the memory of the cluster in use.
+func (s *MetricsSnapshot) MasterMemPercent() float64 {
+	return s.value("master/mem_percent")
+}
+
+// MasterMemTotal returns the memory of the cluster in MB.
+func (s *MetricsSnapshot) MasterMemTotal() float64 {
+	return s.value("master/mem_total")
+}
+
+// MasterMemUsed returns the memory of the cluster in use in MB.
+func (s *MetricsSnapshot) MasterMemUsed() float64 {
+	return s.value("master/mem_used")
+}
+
+// MasterDiskPercent returns the fraction of the disk of the cluster in use.
+func (s *MetricsSnapshot) MasterDiskPercent() float64 {
+	return s.value("master/disk_percent")
+}
+
+// MasterDiskTotal returns the disk of the cluster in MB.
+func (s *MetricsSnapshot) MasterDiskTotal() float64 {
+	return s.value("master/disk_total")
+}
+
+// MasterDiskUsed returns the disk of the cluster in use in MB.
+func (s *MetricsSnapshot) MasterDiskUsed() float64 {
+	return s.value("master/disk_used")
+}
+
+// MasterGPUsPercent returns the fraction of the GPUs of the cluster in use.
+func (s *MetricsSnapshot) MasterGPUsPercent() float64 {
+	return s.value("master/gpus_percent")
+}
+
+// MasterAgentsActive returns the number of active agents.
+func (s *MetricsSnapshot) MasterAgentsActive() uint64 {
+	return s.count("master/slaves_active")
+}
+
+// MasterAgentsInactive returns the number of inactive agents.
+func (s *MetricsSnapshot) MasterAgentsInactive() uint64 {
+	return s.count("master/slaves_inactive")
+}
+
+// MasterAgentsConnected returns the number of connected agents.
+func (s *MetricsSnapshot) MasterAgentsConnected() uint64 {
+	return s.count("master/slaves_connected")
+}
+
+// MasterAgentsDisconnected returns the number of disconnected agents.
+func (s *MetricsSnapshot) MasterAgentsDisconnected() uint64 {
+	return s.count("master/slaves_disconnected")
+}
+
+// MasterFrameworksActive returns the number of active frameworks.
+func (s *MetricsSnapshot) MasterFrameworksActive() uint64 {
+	return s.count("master/frameworks_active")
+}
+
+// MasterFrameworksConnected returns the number of connected frameworks.
+func (s *MetricsSnapshot) MasterFrameworksConnected() uint64 {
+	return s.count("master/frameworks_connected")
+}
+
+// MasterTasksStaging returns the number of staging tasks.
+func (s *MetricsSnapshot) MasterTasksStaging() uint64 {
+	return s.count("master/tasks_staging")
+}
+
+// MasterTasksStarting returns the number of starting tasks.
+func (s *MetricsSnapshot) MasterTasksStarting() uint64 {
+	return s.count("master/tasks_starting")
+}
+
+// MasterTasksRunning returns the number of running tasks.
+func (s *MetricsSnapshot) MasterTasksRunning() uint64 {
+	return s.count("master/tasks_running")
+}
+
+// MasterTasksFinished returns the number of finished tasks.
+func (s *MetricsSnapshot) MasterTasksFinished() uint64 {
+	return s.count("master/tasks_finished")
+}
+
+// MasterTasksFailed returns the number of failed tasks.
+func (s *MetricsSnapshot) MasterTasksFailed() uint64 {
+	return s.count("master/tasks_failed")
+}
+
+// MasterTasksKilled returns the number of killed tasks.
+func (s *MetricsSnapshot) MasterTasksKilled() uint64 {
+	return s.count("master/tasks_killed")
+}
+
+// MasterTasksLost returns the number of lost tasks.
+func (s *MetricsSnapshot) MasterTasksLost() uint64 {
+	return s.count("master/tasks_lost")
+}
+
+// AgentRegistered reports whether the agent is registered with a master.
+func (s *MetricsSnapshot) AgentRegistered() bool {
+	return s.value("slave/registered") == 1
+}
+
+// AgentUptime returns the uptime of the agent.
+func (s *MetricsSnapshot) AgentUptime() time.Duration {
+	return s.seconds("slave/uptime_secs")
+}
+
+// AgentCPUsPercent returns the fraction of the CPUs of the agent in use.
+func (s *MetricsSnapshot) AgentCPUsPercent() float64 {
+	return s.value("slave/cpus_percent")
+}
+
+// AgentCPUsTotal returns the number of CPUs of the agent.
+func (s *MetricsSnapshot) AgentCPUsTotal() float64 {
+	return s.value("slave/cpus_total")
+}
+
+// AgentCPUsUsed returns the number of CPUs of the agent in use.
+func (s *MetricsSnapshot) AgentCPUsUsed() float64 {
+	return s.value("slave/cpus_used")
+}
+
+// AgentMemPercent returns the fraction of the memory of the agent in use.
+func (s *MetricsSnapshot) AgentMemPercent() float64 {
+	return s.value("slave/mem_percent")
+}
+
+// AgentMemTotal returns the memory of the agent in MB.
+func (s *MetricsSnapshot) AgentMemTotal() float64 {
+	return s.value("slave/mem_total")
+}
+
+// AgentMemUsed returns the memory of the agent in use in MB.
+func (s *MetricsSnapshot) AgentMemUsed() float64 {
+	return s.value("slave/mem_used")
+}
+
+// AgentDiskPercent returns the fraction of the disk of the agent in use.
+func (s *MetricsSnapshot) AgentDiskPercent() float64 {
+	return s.value("slave/disk_percent")
+}
+
+// AgentDiskTotal returns the disk of the agent in MB.
+func (s *MetricsSnapshot) AgentDiskTotal() float64 {
+	return s.value("slave/disk_total")
+}
+
+// AgentDiskUsed returns the disk of the agent in use in MB.
+func (s *MetricsSnapshot) AgentDiskUsed() float64 {
+	return s.value("slave/disk_used")
+}
+
+// AgentExecutorsRunning returns the number of running executors.
+func (s *MetricsSnapshot) AgentExecutorsRunning() uint64 {
+	return s.count("slave/executors_running")
+}
+
+// AgentTasksStaging returns the number of staging tasks.
+func (s *MetricsSnapshot) AgentTasksStaging() uint64 {
+	return s.count("slave/tasks_staging")
+}
+
+// AgentTasksStarting returns the number of starting tasks.
+func (s *MetricsSnapshot) AgentTasksStarting() uint64 {
+	return s.count("slave/tasks_starting")
+}
+
+// AgentTasksRunning returns the number of running tasks.
+func (s *MetricsSnapshot) AgentTasksRunning() uint64 {
+	return s.count("slave/tasks_running")
+}
+
+// AgentTasksFailed returns the number of failed tasks.
+func (s *MetricsSnapshot) AgentTasksFailed() uint64 {
+	return s.count("slave/tasks_failed")
+}
+
+// SystemLoad1Min returns the load average of the host over the last minute.
+func (s *MetricsSnapshot) SystemLoad1Min() float64 {
+	return s.value("system/load_1min")
+}
+
+// SystemLoad5Min returns the load average of the host over the last 5 minutes.
+func (s *MetricsSnapshot) SystemLoad5Min() float64 {
+	return s.value("system/load_5min")
+}
+
+// SystemLoad15Min returns the load average of the host over the last 15
+// minutes.
+func (s *MetricsSnapshot) SystemLoad15Min() float64 {
+	return s.value("system/load_15min")
+}
+
+// MetricsDiff holds the changes of the metrics between two snapshots. Create a
+// MetricsDiff with MetricsSnapshot.Diff.
+type MetricsDiff struct {
+	// Elapsed is the time between the two snapshots.
+	Elapsed time.Duration
+	deltas  map[string]float64
+}
+
+// Diff returns the changes of the metrics since the previous snapshot. Only
+// the metrics in both snapshots are compared. Diff returns ErrMetricsReset if
+// the uptime of the master or agent went backwards, in which case the changes
+// of its counters are meaningless, and an error if previous is nil.
+//
+// e.g.
+//
+// 	var diff *MetricsDiff
+// 	diff, err = current.Diff(previous)
+// 	// Messages received per second
+// 	var rate float64
+// 	rate, _ = diff.Rate("master/messages_received")
+func (s *MetricsSnapshot) Diff(previous *MetricsSnapshot) (diff *MetricsDiff, err error) {
+	if previous == nil {
+		err = errors.New("previous snapshot must not be nil")
+		return
+	}
+	for _, uptime := range []string{"master/uptime_secs", "slave/uptime_secs"} {
+		current, ok := s.values[uptime]
+		before, okBefore := previous.values[uptime]
+		if ok && okBefore && current < before {
+			err = ErrMetricsReset
+			return
+		}
+	}
+	diff = &MetricsDiff{Elapsed: s.Time.Sub(previous.Time), deltas: map[string]float64{}}
+	for name, value := range s.values {
+		if before, ok := previous.values[name]; ok {
+			diff.deltas[name] = value - before
+		}
+	}
+	return
+}
+
+// Delta returns the change of the metric with the given name and whether it is
+// in both snapshots.
+func (d *MetricsDiff) Delta(name string) (delta float64, ok bool) {
+	delta, ok = d.deltas[name]
+	return
+}
+
+// Rate returns the change per second of the metric with the given name. ok is
+// false if the metric is not in both snapshots or if no time elapsed between
+// them.
+func (d *MetricsDiff) Rate(name string) (rate float64, ok bool) {
+	var delta float64
+	delta, ok = d.deltas[name]
+	if !ok || d.Elapsed <= 0 {
+		ok = false
+		return
+	}
+	rate = delta / d.Elapsed.Seconds()
+	return
+}
+
+// Rates returns the change per second of the metrics whose names match
+// pattern, keyed by name. The pattern syntax is that of path.Match.
+func (d *MetricsDiff) Rates(pattern string) (rates map[string]float64, err error) {
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	rates = map[string]float64{}
+	for name := range d.deltas {
+		var matched bool
+		matched, _ = path.Match(pattern, name)
+		if !matched {
+			continue
+		}
+		if rate, ok := d.Rate(name); ok {
+			rates[name] = rate
+		}
+	}
+	return
+}
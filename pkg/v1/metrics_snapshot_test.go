@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func newMetrics(values map[string]float64) []*mesos_v1.Metric {
+	metrics := make([]*mesos_v1.Metric, 0, len(values))
+	for name, value := range values {
+		metrics = append(metrics, &mesos_v1.Metric{Name: proto.String(name), Value: proto.Float64(value)})
+	}
+	return metrics
+}
+
+func TestMasterGetMetricsSnapshot(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	responseType := mesos_v1_master.Response_GET_METRICS
+	output, err := proto.Marshal(&mesos_v1_master.Response{
+		Type: &responseType,
+		GetMetrics: &mesos_v1_master.Response_GetMetrics{Metrics: newMetrics(map[string]float64{
+			"master/elected":       1,
+			"master/cpus_percent":  0.75,
+			"master/tasks_running": 12,
+			"master/uptime_secs":   90.5,
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetOutput(output).Handle()
+
+	snapshot, err := s.Master().GetMetricsSnapshot(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.MasterElected() {
+		t.Error("expected the master to be elected")
+	}
+	if snapshot.MasterCPUsPercent() != 0.75 {
+		t.Errorf("expected 0.75, got %f", snapshot.MasterCPUsPercent())
+	}
+	if snapshot.MasterTasksRunning() != 12 {
+		t.Errorf("expected 12, got %d", snapshot.MasterTasksRunning())
+	}
+	if snapshot.MasterUptime() != 90500*time.Millisecond {
+		t.Errorf("expected 1m30.5s, got %s", snapshot.MasterUptime())
+	}
+	if snapshot.AgentRegistered() {
+		t.Error("expected a missing metric to be false")
+	}
+	if _, ok := snapshot.Value("slave/registered"); ok {
+		t.Error("expected slave/registered to be missing")
+	}
+	if len(snapshot.Names()) != 4 || snapshot.Names()[0] != "master/cpus_percent" {
+		t.Errorf("unexpected names %v", snapshot.Names())
+	}
+}
+
+func TestMetricsSnapshotGlob(t *testing.T) {
+	snapshot := NewMetricsSnapshot(newMetrics(map[string]float64{
+		"master/frameworks/a/1/messages_received":         1,
+		"master/frameworks/b/2/messages_received":         2,
+		"master/frameworks/b/2/tasks/active/task_running": 3,
+		"master/messages_received":                        4,
+	}), time.Now())
+
+	values, err := snapshot.Glob("master/frameworks/*/*/messages_received")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values["master/frameworks/b/2/messages_received"] != 2 {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	if _, err = snapshot.Glob("master/[frameworks"); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestMetricsSnapshotDiff(t *testing.T) {
+	start := time.Now()
+	previous := NewMetricsSnapshot(newMetrics(map[string]float64{
+		"master/uptime_secs":       100,
+		"master/messages_received": 50,
+		"master/dropped_messages":  1,
+	}), start)
+	current := NewMetricsSnapshot(newMetrics(map[string]float64{
+		"master/uptime_secs":       110,
+		"master/messages_received": 70,
+		"master/tasks_running":     3,
+	}), start.Add(10*time.Second))
+
+	diff, err := current.Diff(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta, _ := diff.Delta("master/messages_received"); delta != 20 {
+		t.Errorf("expected 20, got %f", delta)
+	}
+	if rate, _ := diff.Rate("master/messages_received"); rate != 2 {
+		t.Errorf("expected 2, got %f", rate)
+	}
+	for _, name := range []string{"master/dropped_messages", "master/tasks_running"} {
+		if _, ok := diff.Rate(name); ok {
+			t.Errorf("expected no rate for %s", name)
+		}
+	}
+	rates, err := diff.Rates("master/messages_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 1 || rates["master/messages_received"] != 2 {
+		t.Errorf("unexpected rates %v", rates)
+	}
+
+	// The master restarted
+	_, err = previous.Diff(current)
+	if !errors.Is(err, ErrMetricsReset) {
+		t.Errorf("expected ErrMetricsReset, got %v", err)
+	}
+}
+
+func TestMetricsSnapshotDiffNilPrevious(t *testing.T) {
+	current := NewMetricsSnapshot(newMetrics(map[string]float64{
+		"master/uptime_secs": 110,
+	}), time.Now())
+
+	diff, err := current.Diff(nil)
+	if err == nil {
+		t.Error("expected an error for a nil previous snapshot")
+	}
+	if diff != nil {
+		t.Errorf("expected no diff, got %v", diff)
+	}
+}
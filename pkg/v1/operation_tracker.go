@@ -0,0 +1,235 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// ErrOperationGone is returned by OperationTracker.Wait and
+// OperationTracker.WaitOperationID when the operation is not reported anymore
+// after it was seen. The master forgets an operation once its framework
+// acknowledges its terminal status, so the operation has finished, but its
+// final state is unknown.
+var ErrOperationGone = errors.New("operation gone before its terminal state was seen")
+
+// operationSource lists the offer operations of a master or agent.
+type operationSource interface {
+	getOperations(ctx context.Context) (operations []*mesos_v1.Operation, err error)
+}
+
+// getOperations returns the operations reported by GetOperations.
+func (m *Master) getOperations(ctx context.Context) (operations []*mesos_v1.Operation, err error) {
+	var response *mesos_v1_master.Response
+	response, err = m.GetOperations(ctx)
+	operations = response.GetGetOperations().GetOperations()
+	return
+}
+
+// getOperations returns the operations reported by GetOperations.
+func (a *Agent) getOperations(ctx context.Context) (operations []*mesos_v1.Operation, err error) {
+	var response *mesos_v1_agent.Response
+	response, err = a.GetOperations(ctx)
+	operations = response.GetGetOperations().GetOperations()
+	return
+}
+
+// IsTerminalOperationState reports whether an operation in the given state
+// will not change state anymore. OPERATION_UNREACHABLE, OPERATION_RECOVERING
+// and OPERATION_UNKNOWN are not terminal, the operation may still finish.
+func IsTerminalOperationState(state mesos_v1.OperationState) bool {
+	switch state {
+	case mesos_v1.OperationState_OPERATION_FINISHED,
+		mesos_v1.OperationState_OPERATION_FAILED,
+		mesos_v1.OperationState_OPERATION_ERROR,
+		mesos_v1.OperationState_OPERATION_DROPPED,
+		mesos_v1.OperationState_OPERATION_GONE_BY_OPERATOR:
+		return true
+	}
+	return false
+}
+
+// OperationTracker waits for offer operations to reach a terminal state, found
+// by their UUID with Wait or by the OperationID their framework gave them with
+// WaitOperationID. Operations without an OperationID, such as the ones
+// triggered by ReserveResource or CreateVolumes, report no status and cannot
+// be tracked. The operator event stream does not carry operation updates, so
+// the OperationTracker polls GET_OPERATIONS. An operation may vanish once it
+// reaches a terminal state, as the master forgets it when its framework
+// acknowledges the status. If that happens between two polls, ErrOperationGone
+// is returned. An operation that was never reported is waited for until ctx
+// is done, so use a ctx with a deadline. Build an OperationTracker with the OperationTrackerBuilder returned by
+// Master.NewOperationTrackerBuilder or Agent.NewOperationTrackerBuilder.
+type OperationTracker struct {
+	source       operationSource
+	pollInterval time.Duration
+}
+
+// OperationTrackerBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct.
+type OperationTrackerBuilder struct {
+	tracker *OperationTracker
+}
+
+// NewOperationTrackerBuilder returns a pointer to an OperationTrackerBuilder
+// for the operations known to the master.
+//
+// e.g.
+//
+// 	var tracker *OperationTracker
+// 	tracker, err = m.NewOperationTrackerBuilder().SetPollInterval(5 * time.Second).Build()
+// 	var status *mesos_v1.OperationStatus
+// 	status, err = tracker.Wait(ctx, operationUUID)
+// 	if status.GetState() != mesos_v1.OperationState_OPERATION_FINISHED {
+// 		log.Printf("operation failed: %s", status.GetMessage())
+// 	}
+func (m *Master) NewOperationTrackerBuilder() *OperationTrackerBuilder {
+	return newOperationTrackerBuilder(m)
+}
+
+// NewOperationTrackerBuilder returns a pointer to an OperationTrackerBuilder
+// for the operations known to the agent.
+func (a *Agent) NewOperationTrackerBuilder() *OperationTrackerBuilder {
+	return newOperationTrackerBuilder(a)
+}
+
+func newOperationTrackerBuilder(source operationSource) *OperationTrackerBuilder {
+	return &OperationTrackerBuilder{
+		tracker: &OperationTracker{
+			source:       source,
+			pollInterval: time.Second,
+		},
+	}
+}
+
+// SetPollInterval sets the time between two GET_OPERATIONS calls and returns a
+// pointer to the OperationTrackerBuilder. If SetPollInterval is not called, it
+// will be set to 1 second.
+//
+// e.g.
+//
+// 	var b *OperationTrackerBuilder = m.NewOperationTrackerBuilder().SetPollInterval(5 * time.Second)
+func (b *OperationTrackerBuilder) SetPollInterval(pollInterval time.Duration) *OperationTrackerBuilder {
+	b.tracker.pollInterval = pollInterval
+	return b
+}
+
+// Build returns a pointer to a constructed OperationTracker.
+func (b *OperationTrackerBuilder) Build() (t *OperationTracker, err error) {
+	if b.tracker.pollInterval <= 0 {
+		err = errors.New("pollInterval must be greater than 0")
+		return
+	}
+	t = b.tracker
+	return
+}
+
+// Wait polls GET_OPERATIONS until the operation with the given UUID reaches a
+// terminal state and returns its final OperationStatus. An operation that
+// failed is not an error, check the state of the status. An operation that is
+// not reported yet is waited for until ctx is done, and one that is not
+// reported anymore returns ErrOperationGone.
+func (t *OperationTracker) Wait(ctx context.Context, uuid *mesos_v1.UUID) (
+	status *mesos_v1.OperationStatus, err error,
+) {
+	if len(uuid.GetValue()) == 0 {
+		err = errors.New("uuid must not be empty")
+		return
+	}
+	status, err = t.wait(ctx, func(operation *mesos_v1.Operation) bool {
+		return bytes.Equal(operation.GetUuid().GetValue(), uuid.GetValue())
+	})
+	return
+}
+
+// WaitOperationID is like Wait, but finds the operation by the framework that
+// applied it and the OperationID it was given by that framework.
+//
+// e.g.
+//
+// 	var status *mesos_v1.OperationStatus
+// 	status, err = tracker.WaitOperationID(ctx, frameworkID, &mesos_v1.OperationID{Value: proto.String("reserve-1")})
+func (t *OperationTracker) WaitOperationID(
+	ctx context.Context, frameworkID *mesos_v1.FrameworkID, operationID *mesos_v1.OperationID,
+) (status *mesos_v1.OperationStatus, err error) {
+	if frameworkID.GetValue() == "" {
+		err = errors.New("frameworkID must not be empty")
+		return
+	}
+	if operationID.GetValue() == "" {
+		err = errors.New("operationID must not be empty")
+		return
+	}
+	status, err = t.wait(ctx, func(operation *mesos_v1.Operation) bool {
+		return operation.GetFrameworkId().GetValue() == frameworkID.GetValue() &&
+			operation.GetInfo().GetId().GetValue() == operationID.GetValue()
+	})
+	return
+}
+
+// wait polls GET_OPERATIONS until the operation selected by match reaches a
+// terminal state or vanishes.
+func (t *OperationTracker) wait(ctx context.Context, match func(operation *mesos_v1.Operation) bool) (
+	status *mesos_v1.OperationStatus, err error,
+) {
+	var ticker *time.Ticker = time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+	var seen bool
+	for {
+		var operations []*mesos_v1.Operation
+		operations, err = t.source.getOperations(ctx)
+		if err != nil {
+			return
+		}
+		var found bool
+		for _, operation := range operations {
+			if !match(operation) {
+				continue
+			}
+			found = true
+			if IsTerminalOperationState(operation.GetLatestStatus().GetState()) {
+				status = operation.GetLatestStatus()
+				return
+			}
+		}
+		if seen && !found {
+			err = ErrOperationGone
+			return
+		}
+		seen = seen || found
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-ticker.C:
+		}
+	}
+}
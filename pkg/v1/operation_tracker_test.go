@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newOperationsServer returns a master whose GET_OPERATIONS reports the
+// operations returned by operations for the nth poll.
+func newOperationsServer(operations func(n int32) []*mesos_v1.Operation) *TestProtobufServer {
+	var polls int32
+	s := NewTestProtobufServer(MasterClient)
+	s.HandleMasterCall(mesos_v1_master.Call_GET_OPERATIONS, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call,
+	) {
+		writeResponse(rw, &mesos_v1_master.Response{
+			Type: mesos_v1_master.Response_GET_OPERATIONS.Enum(),
+			GetOperations: &mesos_v1_master.Response_GetOperations{
+				Operations: operations(atomic.AddInt32(&polls, 1)),
+			},
+		})
+	}).Handle()
+	return s
+}
+
+func TestOperationTrackerWait(t *testing.T) {
+	s := newOperationsServer(func(n int32) []*mesos_v1.Operation {
+		switch {
+		case n == 1:
+			// Not reported yet
+			return nil
+		case n < 4:
+			return []*mesos_v1.Operation{
+				newOperation("other", mesos_v1.OperationState_OPERATION_FAILED),
+				newOperation("operation-1", mesos_v1.OperationState_OPERATION_PENDING),
+			}
+		}
+		return []*mesos_v1.Operation{
+			newOperation("other", mesos_v1.OperationState_OPERATION_FAILED),
+			newOperation("operation-1", mesos_v1.OperationState_OPERATION_FINISHED),
+		}
+	})
+	defer s.Teardown()
+
+	tracker, err := s.Master().NewOperationTrackerBuilder().SetPollInterval(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := tracker.Wait(ctx, &mesos_v1.UUID{Value: []byte("operation-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetState() != mesos_v1.OperationState_OPERATION_FINISHED {
+		t.Errorf("expected OPERATION_FINISHED, got %s", status.GetState())
+	}
+}
+
+func TestOperationTrackerWaitTimesOut(t *testing.T) {
+	s := newOperationsServer(func(n int32) []*mesos_v1.Operation {
+		return []*mesos_v1.Operation{
+			newOperation("operation-1", mesos_v1.OperationState_OPERATION_UNREACHABLE),
+		}
+	})
+	defer s.Teardown()
+
+	tracker, err := s.Master().NewOperationTrackerBuilder().SetPollInterval(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = tracker.Wait(ctx, &mesos_v1.UUID{Value: []byte("operation-1")})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestOperationTrackerWaitOperationGone(t *testing.T) {
+	s := newOperationsServer(func(n int32) []*mesos_v1.Operation {
+		if n < 3 {
+			return []*mesos_v1.Operation{
+				newOperation("operation-1", mesos_v1.OperationState_OPERATION_PENDING),
+			}
+		}
+		// Finished and acknowledged between two polls
+		return nil
+	})
+	defer s.Teardown()
+
+	tracker, err := s.Master().NewOperationTrackerBuilder().SetPollInterval(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = tracker.Wait(ctx, &mesos_v1.UUID{Value: []byte("operation-1")})
+	if err != ErrOperationGone {
+		t.Errorf("expected ErrOperationGone, got %v", err)
+	}
+}
+
+func TestOperationTrackerWaitOperationID(t *testing.T) {
+	newFrameworkOperation := func(framework, id string, state mesos_v1.OperationState) *mesos_v1.Operation {
+		operation := newOperation(framework+"/"+id, state)
+		operation.FrameworkId = &mesos_v1.FrameworkID{Value: proto.String(framework)}
+		operation.Info.Id = &mesos_v1.OperationID{Value: proto.String(id)}
+		return operation
+	}
+	s := newOperationsServer(func(n int32) []*mesos_v1.Operation {
+		state := mesos_v1.OperationState_OPERATION_PENDING
+		if n > 2 {
+			state = mesos_v1.OperationState_OPERATION_FINISHED
+		}
+		return []*mesos_v1.Operation{
+			newFrameworkOperation("other", "reserve-1", mesos_v1.OperationState_OPERATION_FAILED),
+			newFrameworkOperation("framework", "reserve-2", mesos_v1.OperationState_OPERATION_FAILED),
+			newFrameworkOperation("framework", "reserve-1", state),
+		}
+	})
+	defer s.Teardown()
+
+	tracker, err := s.Master().NewOperationTrackerBuilder().SetPollInterval(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := tracker.WaitOperationID(ctx,
+		&mesos_v1.FrameworkID{Value: proto.String("framework")},
+		&mesos_v1.OperationID{Value: proto.String("reserve-1")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.GetState() != mesos_v1.OperationState_OPERATION_FINISHED {
+		t.Errorf("expected OPERATION_FINISHED, got %s", status.GetState())
+	}
+
+	if _, err = tracker.WaitOperationID(ctx, &mesos_v1.FrameworkID{}, nil); err == nil {
+		t.Error("expected an error for an empty frameworkID")
+	}
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func newOperation(uuid string, state mesos_v1.OperationState) *mesos_v1.Operation {
+	operationType := mesos_v1.Offer_Operation_RESERVE
+	return &mesos_v1.Operation{
+		Info:         &mesos_v1.Offer_Operation{Type: &operationType},
+		LatestStatus: &mesos_v1.OperationStatus{State: &state},
+		Uuid:         &mesos_v1.UUID{Value: []byte(uuid)},
+	}
+}
+
+func TestMasterGetOperations(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+	// Setup Response
+	responseType := mesos_v1_master.Response_GET_OPERATIONS
+	response := &mesos_v1_master.Response{
+		Type: &responseType,
+		GetOperations: &mesos_v1_master.Response_GetOperations{
+			Operations: []*mesos_v1.Operation{
+				newOperation("operation-1", mesos_v1.OperationState_OPERATION_FINISHED),
+			},
+		},
+	}
+	output, err := proto.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set Response Handler
+	s.SetOutput(output).Handle()
+
+	// Call
+	data, err := s.Master().GetOperations(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	state := data.GetGetOperations().GetOperations()[0].GetLatestStatus().GetState()
+	if state != mesos_v1.OperationState_OPERATION_FINISHED {
+		t.Errorf("expected OPERATION_FINISHED: got %s", state)
+	}
+}
+
+func TestAgentGetOperations(t *testing.T) {
+	s := NewTestProtobufServer(AgentClient)
+	defer s.Teardown()
+	// Setup Response
+	responseType := mesos_v1_agent.Response_GET_OPERATIONS
+	response := &mesos_v1_agent.Response{
+		Type: &responseType,
+		GetOperations: &mesos_v1_agent.Response_GetOperations{
+			Operations: []*mesos_v1.Operation{
+				newOperation("operation-1", mesos_v1.OperationState_OPERATION_PENDING),
+			},
+		},
+	}
+	output, err := proto.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set Response Handler
+	s.SetOutput(output).Handle()
+
+	// Call
+	data, err := s.Agent().GetOperations(s.Ctx())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	state := data.GetGetOperations().GetOperations()[0].GetLatestStatus().GetState()
+	if state != mesos_v1.OperationState_OPERATION_PENDING {
+		t.Errorf("expected OPERATION_PENDING: got %s", state)
+	}
+}
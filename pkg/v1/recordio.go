@@ -2,8 +2,12 @@ package v1
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
+
+	"github.com/gogo/protobuf/proto"
 )
 
 func readRecordioMessage(reader *bufio.Reader) ([]byte, error) {
@@ -34,3 +38,29 @@ func readRecordioMessage(reader *bufio.Reader) ([]byte, error) {
 
 	return dataBytes, nil
 }
+
+// readRecord reads the next record of a streamed response from reader and
+// decodes it into pb. Errors reading the stream, such as io.EOF at its end,
+// are returned as they are. A malformed record is returned as a
+// StreamDecodeError.
+func (c *client) readRecord(reader *bufio.Reader, callType fmt.Stringer, pb proto.Message) (err error) {
+	var msg []byte
+	msg, err = readRecordioMessage(reader)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok {
+			err = StreamDecodeError{CallType: callType.String(), Err: numErr}
+		}
+		return
+	}
+	err = c.encoding.unmarshal(msg, pb)
+	if err != nil {
+		err = StreamDecodeError{CallType: callType.String(), Err: err}
+	}
+	return
+}
+
+// writeRecordioMessage writes msg to writer as one RecordIO record.
+func writeRecordioMessage(writer io.Writer, msg []byte) (err error) {
+	_, err = fmt.Fprintf(writer, "%d\n%s", len(msg), msg)
+	return
+}
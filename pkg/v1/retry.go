@@ -0,0 +1,282 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// Attempt describes the outcome of a single attempt of a call.
+type Attempt struct {
+	// CallType is the type of the call, e.g. GET_HEALTH.
+	CallType string
+	// Number is the number of the attempt, starting at 1.
+	Number int
+	// Err is the error of the attempt, or nil if it succeeded.
+	Err error
+	// Duration is the time the attempt took.
+	Duration time.Duration
+	// Elapsed is the time since the first attempt of the call started.
+	Elapsed time.Duration
+
+	// rtt is the duration of the first attempt, which estimates the round
+	// trip time to the server.
+	rtt time.Duration
+}
+
+// RetryPolicy decides whether a failed call is sent again. Set it with
+// MasterBuilder.SetRetryPolicy or AgentBuilder.SetRetryPolicy.
+//
+// The built-in policies are the one built by ExponentialBackoffBuilder, which
+// can be limited with WithMaxElapsedTime and IdempotentOnly.
+type RetryPolicy interface {
+	// Retry is called after each failed attempt. It returns whether to send
+	// the call again and how long to wait first. The wait is cut short if
+	// the context of the call is done.
+	Retry(attempt Attempt) (retry bool, wait time.Duration)
+}
+
+// retryable reports whether an attempt failed in a way that sending the call
+// again may fix. A response outside of the 200 range, returned as an
+// HTTPError, is final.
+func retryable(err error) bool {
+	var httpError HTTPError
+	return err != nil && !errors.As(err, &httpError)
+}
+
+// ExponentialBackoffBuilder is a builder that allows you to set optional
+// parameters via its set methods. Call Build to return the final constructed
+// RetryPolicy. Create an ExponentialBackoffBuilder with
+// NewExponentialBackoffBuilder
+type ExponentialBackoffBuilder struct {
+	backoff    *exponentialBackoff
+	maxRetries *int
+}
+
+// NewExponentialBackoffBuilder returns a pointer to an
+// ExponentialBackoffBuilder.
+func NewExponentialBackoffBuilder() *ExponentialBackoffBuilder {
+	return &ExponentialBackoffBuilder{backoff: &exponentialBackoff{jitter: -1}}
+}
+
+// SetMinBackoff sets the wait before the first retry and returns a pointer to
+// the ExponentialBackoffBuilder. The wait doubles with each retry. If
+// SetMinBackoff is not called, it will be set to 100ms.
+//
+// e.g.
+//
+// 	var b *ExponentialBackoffBuilder = NewExponentialBackoffBuilder().SetMinBackoff(time.Second)
+func (b *ExponentialBackoffBuilder) SetMinBackoff(minBackoff time.Duration) *ExponentialBackoffBuilder {
+	b.backoff.minBackoff = minBackoff
+	return b
+}
+
+// SetMaxBackoff sets the longest wait between retries and returns a pointer to
+// the ExponentialBackoffBuilder. If SetMaxBackoff is not called, it will be
+// set to 10s.
+//
+// e.g.
+//
+// 	var b *ExponentialBackoffBuilder = NewExponentialBackoffBuilder().SetMaxBackoff(time.Minute)
+func (b *ExponentialBackoffBuilder) SetMaxBackoff(maxBackoff time.Duration) *ExponentialBackoffBuilder {
+	b.backoff.maxBackoff = maxBackoff
+	return b
+}
+
+// SetMaxRetries sets the number of times a failed call is sent again and
+// returns a pointer to the ExponentialBackoffBuilder. If SetMaxRetries is not
+// called, it will be set to 10.
+//
+// e.g.
+//
+// 	var b *ExponentialBackoffBuilder = NewExponentialBackoffBuilder().SetMaxRetries(3)
+func (b *ExponentialBackoffBuilder) SetMaxRetries(maxRetries int) *ExponentialBackoffBuilder {
+	b.maxRetries = &maxRetries
+	return b
+}
+
+// SetJitter sets the fraction, between 0 and 1, by which each wait is
+// randomly shortened and returns a pointer to the ExponentialBackoffBuilder.
+// Jitter keeps many clients from retrying in lockstep after a shared failure.
+// If SetJitter is not called, it will be set to 0.5.
+//
+// e.g.
+//
+// 	var b *ExponentialBackoffBuilder = NewExponentialBackoffBuilder().SetJitter(1)
+func (b *ExponentialBackoffBuilder) SetJitter(jitter float64) *ExponentialBackoffBuilder {
+	b.backoff.jitter = jitter
+	return b
+}
+
+// Build returns the constructed RetryPolicy.
+func (b *ExponentialBackoffBuilder) Build() (p RetryPolicy, err error) {
+	if b.backoff.minBackoff <= 0 {
+		b.backoff.minBackoff = 100 * time.Millisecond
+	}
+	if b.backoff.maxBackoff <= 0 {
+		b.backoff.maxBackoff = 10 * time.Second
+	}
+	if b.backoff.maxBackoff < b.backoff.minBackoff {
+		err = errors.New("the maximum backoff must not be less than the minimum backoff")
+		return
+	}
+	if b.maxRetries == nil {
+		b.SetMaxRetries(10)
+	}
+	if *b.maxRetries < 0 {
+		err = errors.New("the maximum number of retries must not be negative")
+		return
+	}
+	b.backoff.maxRetries = *b.maxRetries
+	if b.backoff.jitter == -1 {
+		b.backoff.jitter = 0.5
+	}
+	if b.backoff.jitter < 0 || b.backoff.jitter > 1 {
+		err = errors.New("the jitter must be between 0 and 1")
+		return
+	}
+	p = b.backoff
+	return
+}
+
+// exponentialBackoff retries failed calls with exponentially growing, jittered
+// waits. Build one with an ExponentialBackoffBuilder.
+type exponentialBackoff struct {
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	maxRetries int
+	jitter     float64
+}
+
+// Retry implements RetryPolicy.
+func (b *exponentialBackoff) Retry(attempt Attempt) (retry bool, wait time.Duration) {
+	if !retryable(attempt.Err) || attempt.Number > b.maxRetries {
+		return
+	}
+	retry = true
+	var backoff float64 = float64(b.minBackoff) * math.Pow(2, float64(attempt.Number-1))
+	if backoff > float64(b.maxBackoff) {
+		backoff = float64(b.maxBackoff)
+	}
+	wait = time.Duration(backoff * (1 - b.jitter*rand.Float64()))
+	return
+}
+
+// WithMaxElapsedTime returns a RetryPolicy that retries as policy does, until
+// the next attempt would start more than maxElapsedTime after the first.
+//
+// e.g.
+//
+// 	var p RetryPolicy = WithMaxElapsedTime(backoff, 30*time.Second)
+func WithMaxElapsedTime(policy RetryPolicy, maxElapsedTime time.Duration) RetryPolicy {
+	return &maxElapsedTimePolicy{policy: policy, maxElapsedTime: maxElapsedTime}
+}
+
+type maxElapsedTimePolicy struct {
+	policy         RetryPolicy
+	maxElapsedTime time.Duration
+}
+
+// Retry implements RetryPolicy.
+func (p *maxElapsedTimePolicy) Retry(attempt Attempt) (retry bool, wait time.Duration) {
+	retry, wait = p.policy.Retry(attempt)
+	if retry && attempt.Elapsed+wait > p.maxElapsedTime {
+		retry, wait = false, 0
+	}
+	return
+}
+
+// IdempotentOnly returns a RetryPolicy that retries as policy does, but only
+// for calls that are safe to send more than once, such as GET_STATE or
+// READ_FILE. Calls that change the cluster, such as RESERVE_RESOURCES or
+// MARK_AGENT_GONE, may have been applied even though the attempt failed, so
+// they are only retried if the connection to the server could not be made.
+//
+// e.g.
+//
+// 	var p RetryPolicy = IdempotentOnly(backoff)
+func IdempotentOnly(policy RetryPolicy) RetryPolicy {
+	return &idempotentOnlyPolicy{policy: policy}
+}
+
+type idempotentOnlyPolicy struct {
+	policy RetryPolicy
+}
+
+// Retry implements RetryPolicy.
+func (p *idempotentOnlyPolicy) Retry(attempt Attempt) (retry bool, wait time.Duration) {
+	if !isIdempotent(attempt.CallType) && !notSent(attempt.Err) {
+		return
+	}
+	retry, wait = p.policy.Retry(attempt)
+	return
+}
+
+// idempotentCallTypes are the call types, other than GET_*, that only read
+// from the master or agent.
+var idempotentCallTypes map[string]bool = map[string]bool{
+	"SUBSCRIBE":               true,
+	"LIST_FILES":              true,
+	"READ_FILE":               true,
+	"WAIT_NESTED_CONTAINER":   true,
+	"WAIT_CONTAINER":          true,
+	"ATTACH_CONTAINER_OUTPUT": true,
+}
+
+// isIdempotent reports whether a call of the given type is safe to send more
+// than once.
+func isIdempotent(callType string) bool {
+	return strings.HasPrefix(callType, "GET_") || idempotentCallTypes[callType]
+}
+
+// notSent reports whether err shows that the call never reached the server,
+// because the connection to it could not be made.
+func notSent(err error) bool {
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+// binaryExponentialBackoff is a stateless implementation of binary exponential
+// backoff that derives each wait from the attempt number and round-trip time
+// of the Attempt. It is the RetryPolicy used when none is set, retrying up to
+// maxRetries times.
+type binaryExponentialBackoff struct {
+	maxRetries int
+}
+
+// Retry implements RetryPolicy, waiting for the amount of time specified by
+// the binary exponential backoff algorithm described in section 8.2.4 of RFC
+// 2616.
+func (b *binaryExponentialBackoff) Retry(attempt Attempt) (retry bool, wait time.Duration) {
+	if !retryable(attempt.Err) || attempt.Number > b.maxRetries {
+		return
+	}
+	retry = true
+	var retryDuration time.Duration = time.Duration(math.Pow(2, float64(attempt.Number)))
+	wait = attempt.rtt * retryDuration
+	return
+}
@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newDroppingServer returns a server that closes the connection of every
+// request without responding. hits counts the requests it received.
+func newDroppingServer(hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	p, err := NewExponentialBackoffBuilder().
+		SetMinBackoff(time.Second).
+		SetMaxBackoff(4 * time.Second).
+		SetMaxRetries(4).
+		SetJitter(0.5).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("connection reset")
+	for number, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 4 * time.Second} {
+		retry, wait := p.Retry(Attempt{Number: number, Err: failure})
+		if !retry {
+			t.Errorf("expected attempt %d to be retried", number)
+		}
+		if wait > max || wait < max/2 {
+			t.Errorf("expected a wait between %s and %s after attempt %d, got %s", max/2, max, number, wait)
+		}
+	}
+	if retry, _ := p.Retry(Attempt{Number: 5, Err: failure}); retry {
+		t.Error("expected no retry after the maximum number of retries")
+	}
+	if retry, _ := p.Retry(Attempt{Number: 1, Err: HTTPError{StatusCode: http.StatusBadRequest}}); retry {
+		t.Error("expected no retry after an HTTPError")
+	}
+}
+
+func TestExponentialBackoffBuilderErrors(t *testing.T) {
+	if _, err := NewExponentialBackoffBuilder().SetMinBackoff(time.Minute).SetMaxBackoff(time.Second).Build(); err == nil {
+		t.Error("expected an error for a maximum backoff below the minimum, got nil")
+	}
+	if _, err := NewExponentialBackoffBuilder().SetJitter(2).Build(); err == nil {
+		t.Error("expected an error for a jitter above 1, got nil")
+	}
+	if _, err := NewExponentialBackoffBuilder().SetMaxRetries(-1).Build(); err == nil {
+		t.Error("expected an error for negative retries, got nil")
+	}
+}
+
+func TestWithMaxElapsedTime(t *testing.T) {
+	backoff, err := NewExponentialBackoffBuilder().SetMinBackoff(time.Second).SetJitter(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := WithMaxElapsedTime(backoff, 10*time.Second)
+	failure := errors.New("connection reset")
+	if retry, _ := p.Retry(Attempt{Number: 1, Err: failure, Elapsed: 5 * time.Second}); !retry {
+		t.Error("expected a retry within the maximum elapsed time")
+	}
+	if retry, _ := p.Retry(Attempt{Number: 1, Err: failure, Elapsed: 9500 * time.Millisecond}); retry {
+		t.Error("expected no retry past the maximum elapsed time")
+	}
+}
+
+func TestIdempotentOnly(t *testing.T) {
+	backoff, err := NewExponentialBackoffBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := IdempotentOnly(backoff)
+	reset := errors.New("connection reset")
+	refused := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	tests := []struct {
+		callType string
+		err      error
+		retry    bool
+	}{
+		{"GET_STATE", reset, true},
+		{"READ_FILE", reset, true},
+		{"RESERVE_RESOURCES", reset, false},
+		{"MARK_AGENT_GONE", reset, false},
+		{"RESERVE_RESOURCES", refused, true},
+	}
+	for _, test := range tests {
+		if retry, _ := p.Retry(Attempt{CallType: test.callType, Number: 1, Err: test.err}); retry != test.retry {
+			t.Errorf("expected retry %t for %s after %q, got %t", test.retry, test.callType, test.err, retry)
+		}
+	}
+}
+
+func TestMasterRetryPolicyAndAttemptHook(t *testing.T) {
+	var hits int32
+	server := newDroppingServer(&hits)
+	defer server.Close()
+
+	backoff, err := NewExponentialBackoffBuilder().SetMinBackoff(time.Millisecond).SetMaxRetries(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attempts []Attempt
+	m, err := NewMasterBuilder(server.URL).
+		SetRetryPolicy(IdempotentOnly(backoff)).
+		SetAttemptHook(func(attempt Attempt) { attempts = append(attempts, attempt) }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = m.sendSimpleCall(context.Background(), mesos_v1_master.Call_GET_STATE)
+	if !errors.Is(err, ErrRetriesExhausted) {
+		t.Fatalf("expected %v, got %v", ErrRetriesExhausted, err)
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Errorf("expected 3 requests, got %d", atomic.LoadInt32(&hits))
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(attempts))
+	}
+	for i, attempt := range attempts {
+		if attempt.Number != i+1 || attempt.CallType != "GET_STATE" || attempt.Err == nil {
+			t.Errorf("unexpected attempt %+v", attempt)
+		}
+	}
+
+	// A mutating call that reached the server is not sent again
+	atomic.StoreInt32(&hits, 0)
+	callType := mesos_v1_master.Call_RESERVE_RESOURCES
+	_, err = m.client.makeCall(context.Background(), &mesos_v1_master.Call{Type: &callType}, nil)
+	var urlErr *url.Error
+	if errors.Is(err, ErrRetriesExhausted) || !errors.As(err, &urlErr) {
+		t.Fatalf("expected the unwrapped *url.Error, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("expected 1 request, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestRetryWaitRespectsContext(t *testing.T) {
+	var hits int32
+	server := newDroppingServer(&hits)
+	defer server.Close()
+
+	backoff, err := NewExponentialBackoffBuilder().SetMinBackoff(time.Hour).SetMaxBackoff(time.Hour).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAgentBuilder(server.URL).SetRetryPolicy(backoff).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err = a.sendSimpleCall(ctx, mesos_v1_agent.Call_GET_STATE)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the wait to end with the context, took %s", elapsed)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/mesos/go-proto/mesos/v1/agent"
@@ -19,6 +20,14 @@ const (
 	AgentClient
 )
 
+// MasterCallHandler answers a call made to a TestProtobufServer of type
+// MasterClient.
+type MasterCallHandler func(rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call)
+
+// AgentCallHandler answers a call made to a TestProtobufServer of type
+// AgentClient.
+type AgentCallHandler func(rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call)
+
 type TestProtobufServer struct {
 	mux             *http.ServeMux
 	httpClient      *http.Client
@@ -26,11 +35,17 @@ type TestProtobufServer struct {
 	master          *Master
 	agent           *Agent
 	clientType      ClientType
-	input           []byte
 	output          []byte
 	ctx             context.Context
 	cancelFunc      context.CancelFunc
 	closeServerFunc func()
+
+	mu             sync.Mutex
+	masterCalls    []*mesos_v1_master.Call
+	agentCalls     []*mesos_v1_agent.Call
+	masterHandlers map[mesos_v1_master.Call_Type]MasterCallHandler
+	agentHandlers  map[mesos_v1_agent.Call_Type]AgentCallHandler
+	streamHandler  http.HandlerFunc
 }
 
 func NewTestProtobufServer(clientType ClientType) *TestProtobufServer {
@@ -50,6 +65,8 @@ func NewTestProtobufServer(clientType ClientType) *TestProtobufServer {
 		ctx:             ctx,
 		cancelFunc:      cancelFunc,
 		closeServerFunc: server.Close,
+		masterHandlers:  map[mesos_v1_master.Call_Type]MasterCallHandler{},
+		agentHandlers:   map[mesos_v1_agent.Call_Type]AgentCallHandler{},
 	}
 }
 
@@ -63,12 +80,109 @@ func (t *TestProtobufServer) Teardown() {
 func (t *TestProtobufServer) Master() *Master      { return t.master }
 func (t *TestProtobufServer) Agent() *Agent        { return t.agent }
 func (t *TestProtobufServer) Ctx() context.Context { return t.ctx }
+func (t *TestProtobufServer) URL() string          { return t.httpServer.URL }
 
 func (t *TestProtobufServer) SetOutput(b []byte) *TestProtobufServer {
 	t.output = b
 	return t
 }
 
+// HandleMasterCall answers the calls of the given type with handler instead of
+// the output.
+func (t *TestProtobufServer) HandleMasterCall(
+	callType mesos_v1_master.Call_Type, handler MasterCallHandler,
+) *TestProtobufServer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.masterHandlers[callType] = handler
+	return t
+}
+
+// HandleAgentCall answers the calls of the given type with handler instead of
+// the output.
+func (t *TestProtobufServer) HandleAgentCall(
+	callType mesos_v1_agent.Call_Type, handler AgentCallHandler,
+) *TestProtobufServer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.agentHandlers[callType] = handler
+	return t
+}
+
+// HandleStream answers the calls streamed as RecordIO, such as
+// ATTACH_CONTAINER_INPUT, with handler. They are not decoded nor recorded.
+func (t *TestProtobufServer) HandleStream(handler http.HandlerFunc) *TestProtobufServer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.streamHandler = handler
+	return t
+}
+
+// MasterCalls returns the calls received by a MasterClient server, in order.
+func (t *TestProtobufServer) MasterCalls() []*mesos_v1_master.Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*mesos_v1_master.Call{}, t.masterCalls...)
+}
+
+// AgentCalls returns the calls received by an AgentClient server, in order.
+func (t *TestProtobufServer) AgentCalls() []*mesos_v1_agent.Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*mesos_v1_agent.Call{}, t.agentCalls...)
+}
+
+// writeResponse writes response as the protobuf body of rw.
+func writeResponse(rw http.ResponseWriter, response proto.Message) {
+	output, err := proto.Marshal(response)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		rw.Write([]byte(fmt.Sprintf("500 - %s", err)))
+		return
+	}
+	rw.Header().Set("Content-Type", "application/x-protobuf")
+	rw.Write(output)
+}
+
+// dispatch decodes and records the call of req, then answers it with its
+// handler. handled is false if no handler is set for the call.
+func (t *TestProtobufServer) dispatch(rw http.ResponseWriter, req *http.Request) (handled bool, err error) {
+	var b []byte
+	b, err = ioutil.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	switch t.clientType {
+	case MasterClient:
+		call := &mesos_v1_master.Call{}
+		if err = proto.Unmarshal(b, call); err != nil {
+			return
+		}
+		t.mu.Lock()
+		t.masterCalls = append(t.masterCalls, call)
+		handler := t.masterHandlers[call.GetType()]
+		t.mu.Unlock()
+		if handler != nil {
+			handler(rw, req, call)
+			handled = true
+		}
+	case AgentClient:
+		call := &mesos_v1_agent.Call{}
+		if err = proto.Unmarshal(b, call); err != nil {
+			return
+		}
+		t.mu.Lock()
+		t.agentCalls = append(t.agentCalls, call)
+		handler := t.agentHandlers[call.GetType()]
+		t.mu.Unlock()
+		if handler != nil {
+			handler(rw, req, call)
+			handled = true
+		}
+	}
+	return
+}
+
 // Handle does not block. It attaches a generic HandleFunc to the http.ServeMux.
 func (t *TestProtobufServer) Handle() {
 	t.mux.HandleFunc("/api/v1", func(rw http.ResponseWriter, req *http.Request) {
@@ -85,24 +199,21 @@ func (t *TestProtobufServer) Handle() {
 
 		// Only do things in POST
 		if req.Method == http.MethodPost {
-			// Process intput
-			if len(t.input) > 0 {
-				// Read the request body
-				b, err := ioutil.ReadAll(req.Body)
-				if err != nil {
-					clientErrorResponse(err)
-				}
-				// Umarshall the request into a Response
-				switch t.clientType {
-				case MasterClient:
-					err = proto.Unmarshal(b, &mesos_v1_master.Call{})
-				case AgentClient:
-					err = proto.Unmarshal(b, &mesos_v1_agent.Call{})
-				}
-				// If there is an error, send error response
-				if err != nil {
-					clientErrorResponse(err)
-				}
+			t.mu.Lock()
+			streamHandler := t.streamHandler
+			t.mu.Unlock()
+			if streamHandler != nil && req.Header.Get("Content-Type") == recordioContentType {
+				streamHandler(rw, req)
+				return
+			}
+			// Record the call and pass it to its handler, if any
+			handled, err := t.dispatch(rw, req)
+			if err != nil {
+				clientErrorResponse(err)
+				return
+			}
+			if handled {
+				return
 			}
 
 			if len(t.output) > 0 {
@@ -0,0 +1,153 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+)
+
+// StandaloneContainer is a handle on a standalone container launched on an
+// agent with LaunchStandaloneContainer. Call Close when done with the
+// container to remove it from the agent.
+type StandaloneContainer struct {
+	agent        *Agent
+	containerID  *mesos_v1.ContainerID
+	closeTimeout time.Duration
+
+	mu     sync.Mutex
+	exited bool
+	closed bool
+}
+
+// LaunchStandaloneContainer launches a standalone container with
+// LaunchContainer and returns a handle on it. If call has no ContainerId, a
+// random one is generated.
+//
+// e.g.
+//
+// 	var c *StandaloneContainer
+// 	c, err = a.LaunchStandaloneContainer(ctx, &mesos_v1_agent.Call_LaunchContainer{
+// 		Command:   &mesos_v1.CommandInfo{Value: &script},
+// 		Resources: resources,
+// 	})
+// 	defer c.Close()
+// 	var result ContainerResult
+// 	result, err = c.Wait(ctx)
+func (a *Agent) LaunchStandaloneContainer(ctx context.Context, call *mesos_v1_agent.Call_LaunchContainer) (
+	c *StandaloneContainer, err error,
+) {
+	var containerID *mesos_v1.ContainerID = call.GetContainerId()
+	if containerID == nil {
+		containerID, err = newContainerID(nil)
+		if err != nil {
+			return
+		}
+		call = proto.Clone(call).(*mesos_v1_agent.Call_LaunchContainer)
+		call.ContainerId = containerID
+	}
+	err = a.LaunchContainer(ctx, call)
+	if err != nil {
+		return
+	}
+	c = &StandaloneContainer{agent: a, containerID: containerID, closeTimeout: 30 * time.Second}
+	return
+}
+
+// ID returns the ID of the container.
+func (c *StandaloneContainer) ID() *mesos_v1.ContainerID {
+	return c.containerID
+}
+
+// Wait waits until the container exits or ctx is done and returns the result
+// reported by WAIT_CONTAINER.
+func (c *StandaloneContainer) Wait(ctx context.Context) (result ContainerResult, err error) {
+	var response *mesos_v1_agent.Response
+	response, err = c.agent.WaitContainer(ctx, &mesos_v1_agent.Call_WaitContainer{ContainerId: c.containerID})
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.exited = true
+	c.mu.Unlock()
+	var wait *mesos_v1_agent.Response_WaitContainer = response.GetWaitContainer()
+	result.ContainerID = c.containerID
+	result.State = wait.GetState()
+	result.Message = wait.GetMessage()
+	result.exitStatus = wait.ExitStatus
+	return
+}
+
+// Kill sends signal to the container. Call Wait to wait for the container to
+// exit. Sending syscall.SIGKILL destroys the container.
+func (c *StandaloneContainer) Kill(ctx context.Context, signal syscall.Signal) (err error) {
+	var s int32 = int32(signal)
+	err = c.agent.KillContainer(ctx, &mesos_v1_agent.Call_KillContainer{ContainerId: c.containerID, Signal: &s})
+	return
+}
+
+// Close removes the container from the agent. A container that has not been
+// seen to exit by Wait is killed and waited for first, unless the agent reports
+// that it is already gone because it exited on its own. Close is not bound to
+// the context of the other calls, so that the container is removed even if
+// they were canceled. Calling Close more than once does nothing.
+func (c *StandaloneContainer) Close() (err error) {
+	c.mu.Lock()
+	var closed, exited bool = c.closed, c.exited
+	c.closed = true
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(context.Background(), c.closeTimeout)
+	defer cancel()
+	if !exited {
+		err = c.Kill(ctx, syscall.SIGKILL)
+		if err == nil {
+			_, err = c.Wait(ctx)
+		}
+		if err != nil && !containerNotFound(err) {
+			return
+		}
+	}
+	err = c.agent.RemoveContainer(ctx, &mesos_v1_agent.Call_RemoveContainer{ContainerId: c.containerID})
+	return
+}
+
+// containerNotFound returns whether err is the agent reporting that the
+// container it was asked about does not exist.
+func containerNotFound(err error) bool {
+	var httpError HTTPError
+	return errors.As(err, &httpError) && httpError.StatusCode == http.StatusNotFound
+}
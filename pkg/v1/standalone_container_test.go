@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+)
+
+// newContainerAgent returns an agent whose standalone containers exit with
+// status 0 as soon as they are waited for.
+func newContainerAgent() *TestProtobufServer {
+	s := NewTestProtobufServer(AgentClient)
+	s.HandleAgentCall(mesos_v1_agent.Call_WAIT_CONTAINER, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call,
+	) {
+		writeResponse(rw, &mesos_v1_agent.Response{
+			Type: mesos_v1_agent.Response_WAIT_CONTAINER.Enum(),
+			WaitContainer: &mesos_v1_agent.Response_WaitContainer{
+				ExitStatus: proto.Int32(0),
+				State:      mesos_v1.TaskState_TASK_FINISHED.Enum(),
+			},
+		})
+	}).Handle()
+	return s
+}
+
+// callTypes returns the types of calls, in order.
+func callTypes(calls []*mesos_v1_agent.Call) (callTypes []mesos_v1_agent.Call_Type) {
+	for _, call := range calls {
+		callTypes = append(callTypes, call.GetType())
+	}
+	return
+}
+
+func expectCallTypes(t *testing.T, actual []mesos_v1_agent.Call_Type, expected ...mesos_v1_agent.Call_Type) {
+	if len(actual) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected calls %v, got %v", expected, actual)
+		}
+	}
+}
+
+func TestStandaloneContainerLifecycle(t *testing.T) {
+	s := newContainerAgent()
+	defer s.Teardown()
+	a := s.Agent()
+
+	call := &mesos_v1_agent.Call_LaunchContainer{
+		Command: &mesos_v1.CommandInfo{Value: proto.String("fstrim /")},
+	}
+	c, err := a.LaunchStandaloneContainer(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID().GetValue() == "" || c.ID().GetParent() != nil {
+		t.Errorf("expected a generated top-level container ID, got %s", c.ID())
+	}
+	if call.GetContainerId() != nil {
+		t.Error("expected the call not to be modified")
+	}
+
+	if err = c.Kill(context.Background(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode() != 0 || result.State != mesos_v1.TaskState_TASK_FINISHED {
+		t.Errorf("expected exit code 0 and TASK_FINISHED, got %d %s", result.ExitCode(), result.State)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := s.AgentCalls()
+	expectCallTypes(t, callTypes(calls),
+		mesos_v1_agent.Call_LAUNCH_CONTAINER,
+		mesos_v1_agent.Call_KILL_CONTAINER,
+		mesos_v1_agent.Call_WAIT_CONTAINER,
+		mesos_v1_agent.Call_REMOVE_CONTAINER,
+	)
+	if calls[0].GetLaunchContainer().GetContainerId().GetValue() != c.ID().GetValue() {
+		t.Errorf("expected container %s to be launched", c.ID())
+	}
+	if calls[1].GetKillContainer().GetSignal() != int32(syscall.SIGTERM) {
+		t.Errorf("expected SIGTERM, got %d", calls[1].GetKillContainer().GetSignal())
+	}
+	if calls[3].GetRemoveContainer().GetContainerId().GetValue() != c.ID().GetValue() {
+		t.Errorf("expected container %s to be removed", c.ID())
+	}
+}
+
+func TestStandaloneContainerCloseKillsRunningContainer(t *testing.T) {
+	s := newContainerAgent()
+	defer s.Teardown()
+	a := s.Agent()
+
+	containerID := &mesos_v1.ContainerID{Value: proto.String("maintenance-1")}
+	c, err := a.LaunchStandaloneContainer(context.Background(), &mesos_v1_agent.Call_LaunchContainer{
+		ContainerId: containerID,
+		Command:     &mesos_v1.CommandInfo{Value: proto.String("sleep 3600")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID().GetValue() != "maintenance-1" {
+		t.Errorf("expected maintenance-1, got %s", c.ID())
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := s.AgentCalls()
+	expectCallTypes(t, callTypes(calls),
+		mesos_v1_agent.Call_LAUNCH_CONTAINER,
+		mesos_v1_agent.Call_KILL_CONTAINER,
+		mesos_v1_agent.Call_WAIT_CONTAINER,
+		mesos_v1_agent.Call_REMOVE_CONTAINER,
+	)
+	if calls[1].GetKillContainer().GetSignal() != int32(syscall.SIGKILL) {
+		t.Errorf("expected SIGKILL, got %d", calls[1].GetKillContainer().GetSignal())
+	}
+}
+
+func TestStandaloneContainerCloseRemovesExitedContainer(t *testing.T) {
+	s := newContainerAgent()
+	defer s.Teardown()
+	a := s.Agent()
+
+	c, err := a.LaunchStandaloneContainer(context.Background(), &mesos_v1_agent.Call_LaunchContainer{
+		Command: &mesos_v1.CommandInfo{Value: proto.String("true")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The container exits on its own before it is closed, so the agent no
+	// longer knows it
+	notFound := func(rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call) {
+		rw.WriteHeader(http.StatusNotFound)
+	}
+	s.HandleAgentCall(mesos_v1_agent.Call_KILL_CONTAINER, notFound).
+		HandleAgentCall(mesos_v1_agent.Call_WAIT_CONTAINER, notFound)
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := s.AgentCalls()
+	expectCallTypes(t, callTypes(calls),
+		mesos_v1_agent.Call_LAUNCH_CONTAINER,
+		mesos_v1_agent.Call_KILL_CONTAINER,
+		mesos_v1_agent.Call_REMOVE_CONTAINER,
+	)
+}
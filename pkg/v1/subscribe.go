@@ -25,7 +25,11 @@ package v1
 import (
 	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/mesos/go-proto/mesos/v1/master"
@@ -33,12 +37,45 @@ import (
 
 type EventStream chan *mesos_v1_master.Event
 
+// ErrHeartbeatTimeout is returned by Subscribe when the master has not sent an
+// event for longer than the configured number of heartbeat intervals. This
+// usually means the connection has stalled without being closed.
+type ErrHeartbeatTimeout struct {
+	// Interval is the heartbeat interval announced by the master
+	Interval time.Duration
+	// MissedHeartbeats is the number of consecutive heartbeats that were missed
+	MissedHeartbeats int
+}
+
+// Error implements the error interface for ErrHeartbeatTimeout.
+func (e ErrHeartbeatTimeout) Error() string {
+	return fmt.Sprintf(
+		"missed %d heartbeats with an interval of %s", e.MissedHeartbeats, e.Interval,
+	)
+}
+
 // Subscribe subscribes to events on the Mesos mesos_v1_master. This method blocks, so
 // you. likely want to call it in a go routine. Process each *mesos_v1_master.Event on
 // the EventStream by checking the type (you may call GetType() on the
 // *mesos_v1_master.Event), then processing the data as needed. See the test/cmd
 // package for an example.
+//
+// Subscribe returns when the stream ends. If the master announces a heartbeat
+// interval and no event arrives within the number of intervals set by
+// MasterBuilder.SetMaxMissedHeartbeats, Subscribe returns an
+// ErrHeartbeatTimeout. To keep receiving events across disconnections, stalls
+// and leader changes, use a Subscriber instead.
 func (m *Master) Subscribe(ctx context.Context, es EventStream) (err error) {
+	err = m.subscribe(ctx, es, nil)
+	return
+}
+
+// subscribe sends the SUBSCRIBE call and sends each event received on the
+// stream to es until the stream ends. If before is not nil, it is called with
+// each event before the event is sent.
+func (m *Master) subscribe(
+	ctx context.Context, es EventStream, before func(event *mesos_v1_master.Event),
+) (err error) {
 	var httpResponse *http.Response
 	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_SUBSCRIBE
 	var callMsg proto.Message = &mesos_v1_master.Call{Type: &callType}
@@ -49,25 +86,84 @@ func (m *Master) Subscribe(ctx context.Context, es EventStream) (err error) {
 	}
 	var reader *bufio.Reader = bufio.NewReader(httpResponse.Body)
 	defer httpResponse.Body.Close()
+	defer m.client.metrics.openStream(callType.String(), m.client.target)()
+
+	// The heartbeat timer is armed once the heartbeat interval is known. When
+	// it fires, the body is closed to interrupt the blocked read.
+	var heartbeat *heartbeatTimer = &heartbeatTimer{body: httpResponse.Body}
+	defer heartbeat.stop()
 	for {
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 			return
 		default:
-			var msg []byte
-			msg, err = readRecordioMessage(reader)
+			// Unmarshal data into a mesos_v1_master.Event
+			event := &mesos_v1_master.Event{}
+			err = m.client.readRecord(reader, callType, event)
+			if heartbeat.expired() {
+				err = heartbeat.err
+				return
+			}
 			if err != nil {
 				return
 			}
 
-			// Unmarshal data into a mesos_v1_master.Event
-			event := &mesos_v1_master.Event{}
-			err = proto.Unmarshal(msg, event)
-			if err != nil {
+			m.client.metrics.observeEvent(event.GetType().String())
+
+			// Time spent waiting on the consumer is not counted against the
+			// master, so the timer is stopped until the event is delivered.
+			heartbeat.stop()
+			if event.GetType() == mesos_v1_master.Event_SUBSCRIBED {
+				var seconds float64 = event.GetSubscribed().GetHeartbeatIntervalSeconds()
+				heartbeat.err = ErrHeartbeatTimeout{
+					Interval:         time.Duration(seconds * float64(time.Second)),
+					MissedHeartbeats: m.maxMissedHeartbeats,
+				}
+			}
+			if before != nil {
+				before(event)
+			}
+			select {
+			case es <- event:
+			case <-ctx.Done():
+				err = ctx.Err()
 				return
 			}
-			es <- event
+			heartbeat.start()
 		}
 	}
 }
+
+// heartbeatTimer closes an event stream when no event has arrived in time.
+type heartbeatTimer struct {
+	body  io.Closer
+	err   ErrHeartbeatTimeout
+	timer *time.Timer
+	fired int32
+}
+
+// start arms the timer. It does nothing until the heartbeat interval is known
+// or if heartbeat monitoring is disabled.
+func (h *heartbeatTimer) start() {
+	var timeout time.Duration = h.err.Interval * time.Duration(h.err.MissedHeartbeats)
+	if timeout <= 0 {
+		return
+	}
+	h.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&h.fired, 1)
+		h.body.Close()
+	})
+}
+
+// stop disarms the timer.
+func (h *heartbeatTimer) stop() {
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+}
+
+// expired reports whether the timer has fired.
+func (h *heartbeatTimer) expired() bool {
+	return atomic.LoadInt32(&h.fired) == 1
+}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func heartbeatSubscribedEvent(interval time.Duration) *mesos_v1_master.Event {
+	event := subscribedEvent()
+	seconds := interval.Seconds()
+	event.Subscribed.HeartbeatIntervalSeconds = &seconds
+	return event
+}
+
+func heartbeatEvent() *mesos_v1_master.Event {
+	eventType := mesos_v1_master.Event_HEARTBEAT
+	return &mesos_v1_master.Event{Type: &eventType}
+}
+
+// drain receives events until the EventStream is abandoned.
+func drain(ctx context.Context, es EventStream) {
+	for {
+		select {
+		case <-es:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func TestSubscribeHeartbeatTimeout(t *testing.T) {
+	interval := 10 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// Send a few heartbeats, then stall without closing the connection
+		writeRecordioEvents(t, rw, heartbeatSubscribedEvent(interval))
+		for i := 0; i < 5; i++ {
+			time.Sleep(interval)
+			writeRecordioEvents(t, rw, heartbeatEvent())
+		}
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).SetMaxMissedHeartbeats(2).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	es := make(EventStream)
+	go drain(ctx, es)
+
+	err = m.Subscribe(ctx, es)
+	timeout, ok := err.(ErrHeartbeatTimeout)
+	if !ok {
+		t.Fatalf("expected ErrHeartbeatTimeout, got %v", err)
+	}
+	if timeout.Interval != interval {
+		t.Errorf("expected %s, got %s", interval, timeout.Interval)
+	}
+	if timeout.MissedHeartbeats != 2 {
+		t.Errorf("expected 2, got %d", timeout.MissedHeartbeats)
+	}
+}
+
+func TestSubscribeHeartbeatDisabled(t *testing.T) {
+	interval := time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(t, rw, heartbeatSubscribedEvent(interval))
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).SetMaxMissedHeartbeats(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	es := make(EventStream)
+	go drain(ctx, es)
+
+	if err = m.Subscribe(ctx, es); err != context.DeadlineExceeded {
+		t.Errorf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSubscriberResubscribesOnHeartbeatTimeout(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&connections, 1)
+		writeRecordioEvents(t, rw, heartbeatSubscribedEvent(time.Millisecond))
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).SetMinBackoff(time.Millisecond).SetMaxBackoff(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	es := make(EventStream)
+	go s.Subscribe(ctx, es)
+
+	expected := []mesos_v1_master.Event_Type{
+		mesos_v1_master.Event_SUBSCRIBED,
+		EventResync,
+		mesos_v1_master.Event_SUBSCRIBED,
+	}
+	for i, eventType := range expected {
+		select {
+		case e := <-es:
+			if e.GetType() != eventType {
+				t.Fatalf("event %d: expected %s, got %s", i, eventType, e.GetType())
+			}
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n < 2 {
+		t.Errorf("expected at least 2 connections, got %d", n)
+	}
+}
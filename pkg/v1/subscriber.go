@@ -0,0 +1,198 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1/master"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventResync is the type of the synthetic event a Subscriber sends on its
+// EventStream after reconnecting, immediately before the SUBSCRIBED event of
+// the new subscription. Any state built from earlier events may be stale and
+// should be rebuilt from the SUBSCRIBED event that follows.
+//
+// e.g.
+//
+// 	switch e.GetType() {
+// 	case v1.EventResync:
+// 		state.Reset()
+// 	case mesos_v1_master.Event_SUBSCRIBED:
+// 		state.Load(e.GetSubscribed().GetGetState())
+// 	}
+const EventResync mesos_v1_master.Event_Type = -1
+
+// SubscriberBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct. Create a SubscriberBuilder with
+// NewSubscriberBuilder
+type SubscriberBuilder struct {
+	subscriber *Subscriber
+}
+
+// NewSubscriberBuilder returns a pointer to a SubscriberBuilder. The master is
+// the Master used to subscribe and resubscribe to events.
+func NewSubscriberBuilder(master *Master) *SubscriberBuilder {
+	return &SubscriberBuilder{subscriber: &Subscriber{master: master}}
+}
+
+// SetMinBackoff sets the time waited before the first reconnection attempt
+// and returns a pointer to the SubscriberBuilder. The wait doubles after each
+// failed attempt, up to the maximum backoff. If SetMinBackoff is not called,
+// it will be set to 500 milliseconds.
+//
+// e.g.
+//
+// 	var b *SubscriberBuilder = NewSubscriberBuilder(master).SetMinBackoff(time.Second)
+func (b *SubscriberBuilder) SetMinBackoff(minBackoff time.Duration) *SubscriberBuilder {
+	b.subscriber.minBackoff = minBackoff
+	return b
+}
+
+// SetMaxBackoff sets the longest time waited between reconnection attempts
+// and returns a pointer to the SubscriberBuilder. If SetMaxBackoff is not
+// called, it will be set to 30 seconds.
+//
+// e.g.
+//
+// 	var b *SubscriberBuilder = NewSubscriberBuilder(master).SetMaxBackoff(time.Minute)
+func (b *SubscriberBuilder) SetMaxBackoff(maxBackoff time.Duration) *SubscriberBuilder {
+	b.subscriber.maxBackoff = maxBackoff
+	return b
+}
+
+// Build returns a pointer to a constructed Subscriber.
+func (b *SubscriberBuilder) Build() (s *Subscriber, err error) {
+	if b.subscriber.master == nil {
+		err = errors.New("a Master is required")
+		return
+	}
+	if b.subscriber.minBackoff <= 0 {
+		b.subscriber.minBackoff = 500 * time.Millisecond
+	}
+	if b.subscriber.maxBackoff <= 0 {
+		b.subscriber.maxBackoff = 30 * time.Second
+	}
+	if b.subscriber.maxBackoff < b.subscriber.minBackoff {
+		err = errors.New("the maximum backoff must not be less than the minimum backoff")
+		return
+	}
+	s = b.subscriber
+	return
+}
+
+// Subscriber keeps a subscription to the events of a Master alive. When the
+// event stream ends, or stalls for longer than the Master's heartbeat timeout
+// (see ErrHeartbeatTimeout), the Subscriber resubscribes with exponential
+// backoff, following the Master to the new leader if leadership has changed.
+// Build a Subscriber with a SubscriberBuilder.
+type Subscriber struct {
+	master     *Master
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Subscribe subscribes to events on the Mesos master and sends them on the
+// EventStream, resubscribing whenever the stream ends. Each new subscription
+// starts with an EventResync event followed by the SUBSCRIBED event holding a
+// fresh snapshot of the cluster state; the first subscription starts with the
+// SUBSCRIBED event alone. Events sent while disconnected are lost, so consumers
+// should rebuild their state from the snapshot.
+//
+// Subscribe blocks until the context is done, so you likely want to call it
+// in a go routine. It returns early, without resubscribing, if the master
+// rejects the subscription, e.g. with ErrUnauthorized, or sends events that
+// cannot be decoded, since subscribing again would fail the same way.
+func (s *Subscriber) Subscribe(ctx context.Context, es EventStream) (err error) {
+	// With tracing configured on the Master, the subscription is covered by a
+	// single span that records each subscription and reconnect as an event.
+	var span trace.Span
+	ctx, span = s.master.client.startStreamSpan(ctx, mesos_v1_master.Call_SUBSCRIBE.String())
+	defer func() { endSpan(span, nil) }()
+
+	var backoff time.Duration = s.minBackoff
+	var resubscribing bool
+	for {
+		var subscribed bool
+		err = s.master.subscribe(ctx, es, func(event *mesos_v1_master.Event) {
+			if event.GetType() != mesos_v1_master.Event_SUBSCRIBED {
+				return
+			}
+			subscribed = true
+			addEvent(span, "subscribed")
+			if resubscribing {
+				var eventType mesos_v1_master.Event_Type = EventResync
+				select {
+				case es <- &mesos_v1_master.Event{Type: &eventType}:
+				case <-ctx.Done():
+				}
+			}
+		})
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
+		if !resubscribable(err) {
+			return
+		}
+
+		// Start over from the minimum backoff once a subscription succeeds
+		if subscribed {
+			resubscribing = true
+			backoff = s.minBackoff
+		}
+		addEvent(span, "reconnect",
+			attributeError.String(fmt.Sprint(err)),
+			attributeBackoff.String(backoff.String()),
+		)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// resubscribable reports whether a subscription that ended with err may
+// succeed if sent again. Errors that retryable considers final end the
+// Subscriber, except for the server errors and redirects a master responds
+// with while leadership changes. A stream that could not be decoded is not
+// resubscribed to either.
+func resubscribable(err error) bool {
+	if err == nil || retryable(err) {
+		return !errors.Is(err, ErrStreamDecode)
+	}
+	var httpError HTTPError
+	return errors.As(err, &httpError) &&
+		(httpError.StatusCode >= http.StatusInternalServerError || errors.Is(err, ErrNotLeader))
+}
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// writeRecordioEvents writes each event to rw in RecordIO format. It runs on
+// the goroutine of a handler, so it reports errors with t.Error.
+func writeRecordioEvents(t *testing.T, rw http.ResponseWriter, events ...*mesos_v1_master.Event) {
+	for _, event := range events {
+		b, err := proto.Marshal(event)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		fmt.Fprintf(rw, "%d\n", len(b))
+		rw.Write(b)
+	}
+	rw.(http.Flusher).Flush()
+}
+
+func subscribedEvent() *mesos_v1_master.Event {
+	eventType := mesos_v1_master.Event_SUBSCRIBED
+	return &mesos_v1_master.Event{Type: &eventType, Subscribed: &mesos_v1_master.Event_Subscribed{}}
+}
+
+func agentRemovedEvent(agentID string) *mesos_v1_master.Event {
+	eventType := mesos_v1_master.Event_AGENT_REMOVED
+	return &mesos_v1_master.Event{
+		Type: &eventType,
+		AgentRemoved: &mesos_v1_master.Event_AgentRemoved{
+			AgentId: &mesos_v1.AgentID{Value: &agentID},
+		},
+	}
+}
+
+func TestSubscriberBuild(t *testing.T) {
+	m, _ := NewMasterBuilder("test-url").Build()
+	if _, err := NewSubscriberBuilder(m).Build(); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewSubscriberBuilder(nil).Build(); err == nil {
+		t.Error("expected an error when the Master is nil, got nil")
+	}
+	_, err := NewSubscriberBuilder(m).SetMinBackoff(time.Minute).SetMaxBackoff(time.Second).Build()
+	if err == nil {
+		t.Error("expected an error when the maximum backoff is less than the minimum backoff, got nil")
+	}
+}
+
+func TestSubscriberResubscribes(t *testing.T) {
+	// Each connection sends a snapshot and an event, then drops
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		writeRecordioEvents(t, rw, subscribedEvent(), agentRemovedEvent(fmt.Sprintf("agent-%d", n)))
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).SetMinBackoff(time.Millisecond).SetMaxBackoff(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	es := make(EventStream)
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.Subscribe(ctx, es) }()
+
+	expected := []mesos_v1_master.Event_Type{
+		mesos_v1_master.Event_SUBSCRIBED,
+		mesos_v1_master.Event_AGENT_REMOVED,
+		EventResync,
+		mesos_v1_master.Event_SUBSCRIBED,
+		mesos_v1_master.Event_AGENT_REMOVED,
+	}
+	for i, eventType := range expected {
+		select {
+		case e := <-es:
+			if e.GetType() != eventType {
+				t.Fatalf("event %d: expected %s, got %s", i, eventType, e.GetType())
+			}
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Errorf("expected %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestSubscriberStopsOnUnauthorized(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&connections, 1)
+		rw.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).SetMinBackoff(time.Millisecond).SetMaxBackoff(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = s.Subscribe(ctx, make(EventStream))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected %s, got %v", ErrUnauthorized, err)
+	}
+	if atomic.LoadInt32(&connections) != 1 {
+		t.Errorf("expected 1 subscription, got %d", atomic.LoadInt32(&connections))
+	}
+}
+
+func TestSubscriberResubscribesOnServiceUnavailable(t *testing.T) {
+	// No leader is elected for the first connection
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&connections, 1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			rw.Write([]byte("No leader elected"))
+			return
+		}
+		writeRecordioEvents(t, rw, subscribedEvent())
+	}))
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetHTTPClient(server.Client()).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).SetMinBackoff(time.Millisecond).SetMaxBackoff(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	es := make(EventStream)
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.Subscribe(ctx, es) }()
+	select {
+	case e := <-es:
+		if e.GetType() != mesos_v1_master.Event_SUBSCRIBED {
+			t.Errorf("expected SUBSCRIBED, got %s", e.GetType())
+		}
+	case err := <-errChan:
+		t.Fatalf("expected the Subscriber to resubscribe, got %v", err)
+	}
+	cancel()
+	<-errChan
+}
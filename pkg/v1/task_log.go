@@ -0,0 +1,428 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// ErrTaskNotFound is returned by TaskLog.Open when the master does not know
+// the task.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrAgentNotFound is returned when the master does not know the agent of a
+// task.
+var ErrAgentNotFound = errors.New("agent not found")
+
+// TaskLog reads the stdout or stderr file in the sandbox of a task. Build a
+// TaskLog with a TaskLogBuilder.
+type TaskLog struct {
+	cluster      *Cluster
+	taskID       string
+	file         string
+	follow       bool
+	offset       int64
+	tailLines    int
+	pollInterval time.Duration
+	chunkSize    int64
+}
+
+// TaskLogBuilder is a builder that takes some manditory parameters and
+// allows you to set optional parameters via its set methods. Call Build to
+// return the final constructed struct. Create a TaskLogBuilder with
+// NewTaskLogBuilder
+type TaskLogBuilder struct {
+	log *TaskLog
+}
+
+// NewTaskLogBuilder returns a pointer to a TaskLogBuilder for the task with
+// the given ID. The task and its agent are found through the master of the
+// Cluster.
+func NewTaskLogBuilder(cluster *Cluster, taskID string) *TaskLogBuilder {
+	return &TaskLogBuilder{
+		log: &TaskLog{
+			cluster:      cluster,
+			taskID:       taskID,
+			file:         "stdout",
+			pollInterval: time.Second,
+			chunkSize:    64 * 1024,
+		},
+	}
+}
+
+// SetFile sets the name of the file read from the sandbox, usually stdout or
+// stderr, and returns a pointer to the TaskLogBuilder. If SetFile is not
+// called, it will be set to stdout.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetFile("stderr")
+func (b *TaskLogBuilder) SetFile(file string) *TaskLogBuilder {
+	b.log.file = file
+	return b
+}
+
+// SetFollow sets whether the reader waits for new bytes at the end of the
+// file, like tail --follow, and returns a pointer to the TaskLogBuilder. If
+// SetFollow is not called, the reader returns io.EOF at the end of the file.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetFollow(true)
+func (b *TaskLogBuilder) SetFollow(follow bool) *TaskLogBuilder {
+	b.log.follow = follow
+	return b
+}
+
+// SetOffset sets the offset in bytes the reader starts at and returns a
+// pointer to the TaskLogBuilder. If SetOffset is not called, the reader starts
+// at the beginning of the file. SetOffset cannot be combined with
+// SetTailLines.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetOffset(4096)
+func (b *TaskLogBuilder) SetOffset(offset int64) *TaskLogBuilder {
+	b.log.offset = offset
+	return b
+}
+
+// SetTailLines sets the number of lines at the end of the file the reader
+// starts with, like tail --lines, and returns a pointer to the TaskLogBuilder.
+// SetTailLines cannot be combined with SetOffset.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetTailLines(100)
+func (b *TaskLogBuilder) SetTailLines(tailLines int) *TaskLogBuilder {
+	b.log.tailLines = tailLines
+	return b
+}
+
+// SetPollInterval sets how often a following reader checks for new bytes and
+// returns a pointer to the TaskLogBuilder. If SetPollInterval is not called,
+// it will be set to 1 second.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetPollInterval(250 * time.Millisecond)
+func (b *TaskLogBuilder) SetPollInterval(pollInterval time.Duration) *TaskLogBuilder {
+	b.log.pollInterval = pollInterval
+	return b
+}
+
+// SetChunkSize sets the maximum number of bytes read with each ReadFile call
+// and returns a pointer to the TaskLogBuilder. If SetChunkSize is not called,
+// it will be set to 64KiB.
+//
+// e.g.
+//
+// 	var b *TaskLogBuilder = NewTaskLogBuilder(cluster, "my-task").SetChunkSize(1024 * 1024)
+func (b *TaskLogBuilder) SetChunkSize(chunkSize int64) *TaskLogBuilder {
+	b.log.chunkSize = chunkSize
+	return b
+}
+
+// Build returns a pointer to a constructed TaskLog.
+func (b *TaskLogBuilder) Build() (l *TaskLog, err error) {
+	switch {
+	case b.log.cluster == nil:
+		err = errors.New("cluster must not be nil")
+	case b.log.taskID == "":
+		err = errors.New("taskID must not be empty")
+	case b.log.file == "":
+		err = errors.New("file must not be empty")
+	case b.log.offset < 0:
+		err = errors.New("offset must not be negative")
+	case b.log.tailLines < 0:
+		err = errors.New("tailLines must not be negative")
+	case b.log.offset > 0 && b.log.tailLines > 0:
+		err = errors.New("offset and tailLines cannot be combined")
+	case b.log.pollInterval <= 0:
+		err = errors.New("pollInterval must be greater than 0")
+	case b.log.chunkSize <= 0:
+		err = errors.New("chunkSize must be greater than 0")
+	}
+	if err != nil {
+		return
+	}
+	l = b.log
+	return
+}
+
+// Open finds the task and its agent and returns a reader of the file. The
+// reader stops when ctx is done or when it is closed. Open returns
+// ErrTaskNotFound if the master does not know the task.
+//
+// A following reader detects that the file was rotated when it becomes
+// smaller than the bytes already read. It then reads the rest of the rotated
+// file, FILE.1, before starting over at the beginning of the new file.
+//
+// e.g.
+//
+// 	var l *TaskLog
+// 	l, err = NewTaskLogBuilder(cluster, "my-task").SetFollow(true).SetTailLines(10).Build()
+// 	var r io.ReadCloser
+// 	r, err = l.Open(ctx)
+// 	defer r.Close()
+// 	_, err = io.Copy(os.Stdout, r)
+func (l *TaskLog) Open(ctx context.Context) (reader io.ReadCloser, err error) {
+	var a *Agent
+	var sandbox string
+	a, sandbox, err = l.locate(ctx)
+	if err != nil {
+		return
+	}
+	var r *taskLogReader = &taskLogReader{
+		agent:        a,
+		path:         sandbox + "/" + l.file,
+		offset:       l.offset,
+		follow:       l.follow,
+		pollInterval: l.pollInterval,
+		chunkSize:    l.chunkSize,
+		closed:       make(chan struct{}),
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	if l.tailLines > 0 {
+		r.offset, err = r.tailOffset(l.tailLines)
+		if err != nil {
+			r.Close()
+			return
+		}
+	}
+	reader = r
+	return
+}
+
+// locate returns the Agent running the task and the virtual path of its
+// sandbox.
+func (l *TaskLog) locate(ctx context.Context) (a *Agent, sandbox string, err error) {
+	var response *mesos_v1_master.Response
+	response, err = l.cluster.master.GetTasks(ctx)
+	if err != nil {
+		return
+	}
+	var task *mesos_v1.Task
+	var tasks *mesos_v1_master.Response_GetTasks = response.GetGetTasks()
+	for _, list := range [][]*mesos_v1.Task{tasks.GetTasks(), tasks.GetCompletedTasks(), tasks.GetUnreachableTasks()} {
+		for _, t := range list {
+			if t.GetTaskId().GetValue() == l.taskID {
+				task = t
+				break
+			}
+		}
+		if task != nil {
+			break
+		}
+	}
+	if task == nil {
+		err = fmt.Errorf("%w: %s", ErrTaskNotFound, l.taskID)
+		return
+	}
+
+	var agents []*mesos_v1_master.Response_GetAgents_Agent
+	agents, err = l.cluster.Agents(ctx, func(agent *mesos_v1_master.Response_GetAgents_Agent) bool {
+		return agent.GetAgentInfo().GetId().GetValue() == task.GetAgentId().GetValue()
+	})
+	if err != nil {
+		return
+	}
+	if len(agents) == 0 {
+		err = fmt.Errorf("%w: %s", ErrAgentNotFound, task.GetAgentId().GetValue())
+		return
+	}
+	a, err = l.cluster.Agent(agents[0].GetAgentInfo())
+	if err != nil {
+		return
+	}
+
+	// Tasks launched by the command executor run in the sandbox of an
+	// executor with the ID of the task. The tasks of a task group have their
+	// own sandbox within the sandbox of their executor.
+	var executorID string = task.GetExecutorId().GetValue()
+	if executorID == "" {
+		executorID = l.taskID
+	}
+	sandbox = fmt.Sprintf(
+		"/frameworks/%s/executors/%s/runs/latest", task.GetFrameworkId().GetValue(), executorID,
+	)
+	if executorID != l.taskID {
+		var nested string = sandbox + "/tasks/" + l.taskID
+		if _, listErr := a.ListFiles(ctx, &mesos_v1_agent.Call_ListFiles{Path: &nested}); listErr == nil {
+			sandbox = nested
+		}
+	}
+	return
+}
+
+// taskLogReader reads a file of a sandbox with ReadFile calls.
+type taskLogReader struct {
+	agent        *Agent
+	path         string
+	offset       int64
+	follow       bool
+	pollInterval time.Duration
+	chunkSize    int64
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	buf []byte
+	// rotated is the offset to continue reading the rotated file at, or 0
+	rotated int64
+}
+
+// readFile reads up to length bytes of path at offset and returns them with
+// the size of the file.
+func (r *taskLogReader) readFile(path string, offset int64, length int64) (data []byte, size int64, err error) {
+	data, size, err = r.agent.readFile(r.ctx, path, offset, length)
+	return
+}
+
+// tailOffset returns the offset of the start of the last n lines of the file.
+// A newline at the very end of the file does not start a new line.
+func (r *taskLogReader) tailOffset(n int) (offset int64, err error) {
+	var size int64
+	_, size, err = r.readFile(r.path, 0, 0)
+	if err != nil {
+		return
+	}
+	var end int64 = size
+	var newlines int
+	for end > 0 {
+		var start int64 = end - r.chunkSize
+		if start < 0 {
+			start = 0
+		}
+		var data []byte
+		data, _, err = r.readFile(r.path, start, end-start)
+		if err != nil {
+			return
+		}
+		for i := len(data) - 1; i >= 0; i-- {
+			if data[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				offset = start + int64(i) + 1
+				return
+			}
+		}
+		end = start
+	}
+	return
+}
+
+// Read implements io.Reader.
+func (r *taskLogReader) Read(p []byte) (n int, err error) {
+	select {
+	case <-r.closed:
+		err = os.ErrClosed
+		return
+	default:
+	}
+	for len(r.buf) == 0 {
+		err = r.fill()
+		if err != nil {
+			select {
+			case <-r.closed:
+				// Closing a following reader ends the stream
+				err = io.EOF
+			default:
+			}
+			return
+		}
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return
+}
+
+// fill reads the next chunk of the file into buf. It waits for new bytes if
+// the reader follows the file.
+func (r *taskLogReader) fill() (err error) {
+	for {
+		if r.rotated > 0 {
+			var data []byte
+			data, _, err = r.readFile(r.path+".1", r.rotated, r.chunkSize)
+			if err != nil || len(data) == 0 {
+				// The rotated file is gone or fully read
+				r.rotated, err = 0, nil
+			} else {
+				r.rotated += int64(len(data))
+				r.buf = data
+				return
+			}
+		}
+
+		var data []byte
+		var size int64
+		data, size, err = r.readFile(r.path, r.offset, r.chunkSize)
+		if err != nil {
+			return
+		}
+		if len(data) > 0 {
+			r.offset += int64(len(data))
+			r.buf = data
+			return
+		}
+		if size < r.offset {
+			r.rotated, r.offset = r.offset, 0
+			continue
+		}
+		if !r.follow {
+			err = io.EOF
+			return
+		}
+		var timer *time.Timer = time.NewTimer(r.pollInterval)
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			err = r.ctx.Err()
+			return
+		}
+	}
+}
+
+// Close implements io.Closer. It interrupts a Read that waits for new bytes.
+func (r *taskLogReader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		r.cancel()
+	})
+	return nil
+}
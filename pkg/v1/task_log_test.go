@@ -0,0 +1,341 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// fakeFiles is an agent that serves LIST_FILES and READ_FILE from memory. If
+// onRead is set, it is called with the path of every READ_FILE call served.
+type fakeFiles struct {
+	*TestProtobufServer
+
+	mu     sync.Mutex
+	files  map[string][]byte
+	onRead func(path string)
+}
+
+func newFakeFiles() (f *fakeFiles) {
+	f = &fakeFiles{TestProtobufServer: NewTestProtobufServer(AgentClient), files: map[string][]byte{}}
+	f.HandleAgentCall(mesos_v1_agent.Call_LIST_FILES, f.handle).
+		HandleAgentCall(mesos_v1_agent.Call_READ_FILE, f.handle).
+		Handle()
+	return
+}
+
+func (f *fakeFiles) set(path string, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[path] = []byte(data)
+}
+
+func (f *fakeFiles) appendTo(path string, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[path] = append(f.files[path], data...)
+}
+
+func (f *fakeFiles) rename(from string, to string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[to] = f.files[from]
+	delete(f.files, from)
+}
+
+func (f *fakeFiles) respond(call *mesos_v1_agent.Call) (response *mesos_v1_agent.Response, found bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch call.GetType() {
+	case mesos_v1_agent.Call_LIST_FILES:
+		dir := strings.TrimSuffix(call.GetListFiles().GetPath(), "/") + "/"
+		children := map[string]*mesos_v1.FileInfo{}
+		if data, ok := f.files[call.GetListFiles().GetPath()]; ok {
+			found = true
+			children[call.GetListFiles().GetPath()] = &mesos_v1.FileInfo{
+				Path: proto.String(call.GetListFiles().GetPath()), Size: proto.Uint64(uint64(len(data))), Mode: proto.Uint32(0100644),
+			}
+		}
+		for path, data := range f.files {
+			if !strings.HasPrefix(path, dir) {
+				continue
+			}
+			found = true
+			rest := strings.TrimPrefix(path, dir)
+			if i := strings.Index(rest, "/"); i >= 0 {
+				children[dir+rest[:i]] = &mesos_v1.FileInfo{
+					Path: proto.String(dir + rest[:i]), Mode: proto.Uint32(0040755),
+				}
+				continue
+			}
+			children[path] = &mesos_v1.FileInfo{
+				Path: proto.String(path), Size: proto.Uint64(uint64(len(data))), Mode: proto.Uint32(0100640),
+				Mtime: &mesos_v1.TimeInfo{Nanoseconds: proto.Int64(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano())},
+			}
+		}
+		var paths []string
+		for path := range children {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		listFiles := &mesos_v1_agent.Response_ListFiles{}
+		for _, path := range paths {
+			listFiles.FileInfos = append(listFiles.FileInfos, children[path])
+		}
+		response = &mesos_v1_agent.Response{Type: mesos_v1_agent.Response_LIST_FILES.Enum(), ListFiles: listFiles}
+	case mesos_v1_agent.Call_READ_FILE:
+		var data []byte
+		data, found = f.files[call.GetReadFile().GetPath()]
+		size := uint64(len(data))
+		offset := call.GetReadFile().GetOffset()
+		if offset > size {
+			offset = size
+		}
+		end := size
+		if call.GetReadFile().Length != nil && offset+call.GetReadFile().GetLength() < size {
+			end = offset + call.GetReadFile().GetLength()
+		}
+		response = &mesos_v1_agent.Response{
+			Type:     mesos_v1_agent.Response_READ_FILE.Enum(),
+			ReadFile: &mesos_v1_agent.Response_ReadFile{Size: &size, Data: append([]byte{}, data[offset:end]...)},
+		}
+	}
+	return
+}
+
+func (f *fakeFiles) handle(rw http.ResponseWriter, req *http.Request, call *mesos_v1_agent.Call) {
+	response, found := f.respond(call)
+	if !found {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if f.onRead != nil && call.GetType() == mesos_v1_agent.Call_READ_FILE {
+		f.onRead(call.GetReadFile().GetPath())
+	}
+	writeResponse(rw, response)
+}
+
+// newTaskMaster returns a master that knows the given tasks, all running on
+// the agent served at agentURL with the ID agent-1.
+func newTaskMaster(t *testing.T, agentURL string, tasks ...*mesos_v1.Task) *TestProtobufServer {
+	agentAddress, err := url.Parse(agentURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(agentAddress.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTestProtobufServer(MasterClient)
+	s.HandleMasterCall(mesos_v1_master.Call_GET_TASKS, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call,
+	) {
+		writeResponse(rw, &mesos_v1_master.Response{
+			Type:     mesos_v1_master.Response_GET_TASKS.Enum(),
+			GetTasks: &mesos_v1_master.Response_GetTasks{Tasks: tasks},
+		})
+	}).HandleMasterCall(mesos_v1_master.Call_GET_AGENTS, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call,
+	) {
+		writeResponse(rw, &mesos_v1_master.Response{
+			Type: mesos_v1_master.Response_GET_AGENTS.Enum(),
+			GetAgents: &mesos_v1_master.Response_GetAgents{Agents: []*mesos_v1_master.Response_GetAgents_Agent{{
+				AgentInfo: &mesos_v1.AgentInfo{
+					Id:       &mesos_v1.AgentID{Value: proto.String("agent-1")},
+					Hostname: proto.String(agentAddress.Hostname()),
+					Port:     proto.Int32(int32(port)),
+				},
+				Active:  proto.Bool(true),
+				Version: proto.String("1.11.0"),
+			}}},
+		})
+	}).Handle()
+	return s
+}
+
+func newTask(taskID string, executorID string) *mesos_v1.Task {
+	task := &mesos_v1.Task{
+		Name:        proto.String(taskID),
+		TaskId:      &mesos_v1.TaskID{Value: proto.String(taskID)},
+		FrameworkId: &mesos_v1.FrameworkID{Value: proto.String("framework-1")},
+		AgentId:     &mesos_v1.AgentID{Value: proto.String("agent-1")},
+		State:       mesos_v1.TaskState_TASK_RUNNING.Enum(),
+	}
+	if executorID != "" {
+		task.ExecutorId = &mesos_v1.ExecutorID{Value: proto.String(executorID)}
+	}
+	return task
+}
+
+// newTaskLogFixture returns the files of an agent and a Cluster whose master
+// knows the given tasks.
+func newTaskLogFixture(t *testing.T, tasks ...*mesos_v1.Task) (files *fakeFiles, cluster *Cluster, teardown func()) {
+	files = newFakeFiles()
+	master := newTaskMaster(t, files.URL(), tasks...)
+	cluster = newTestCluster(t, master.URL(), nil)
+	teardown = func() {
+		master.Teardown()
+		files.Teardown()
+	}
+	return
+}
+
+func readTaskLog(t *testing.T, b *TaskLogBuilder) string {
+	l, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := l.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+const commandSandbox = "/frameworks/framework-1/executors/task-1/runs/latest"
+
+func TestTaskLogRead(t *testing.T) {
+	files, cluster, teardown := newTaskLogFixture(t, newTask("task-1", ""))
+	defer teardown()
+	files.set(commandSandbox+"/stdout", "hello\nworld\n")
+	files.set(commandSandbox+"/stderr", "oops\n")
+
+	if out := readTaskLog(t, NewTaskLogBuilder(cluster, "task-1").SetChunkSize(4)); out != "hello\nworld\n" {
+		t.Errorf("expected stdout, got %q", out)
+	}
+	if out := readTaskLog(t, NewTaskLogBuilder(cluster, "task-1").SetFile("stderr")); out != "oops\n" {
+		t.Errorf("expected stderr, got %q", out)
+	}
+	if out := readTaskLog(t, NewTaskLogBuilder(cluster, "task-1").SetOffset(6)); out != "world\n" {
+		t.Errorf("expected the bytes after the offset, got %q", out)
+	}
+}
+
+func TestTaskLogTailLines(t *testing.T) {
+	files, cluster, teardown := newTaskLogFixture(t, newTask("task-1", ""))
+	defer teardown()
+
+	tests := []struct {
+		data     string
+		lines    int
+		expected string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\nc\n", 5, "a\nb\nc\n"},
+		{"", 3, ""},
+	}
+	for _, test := range tests {
+		files.set(commandSandbox+"/stdout", test.data)
+		out := readTaskLog(t, NewTaskLogBuilder(cluster, "task-1").SetTailLines(test.lines).SetChunkSize(2))
+		if out != test.expected {
+			t.Errorf("%q with %d lines: expected %q, got %q", test.data, test.lines, test.expected, out)
+		}
+	}
+}
+
+func TestTaskLogTaskGroupSandbox(t *testing.T) {
+	files, cluster, teardown := newTaskLogFixture(t, newTask("task-1", "default-executor"))
+	defer teardown()
+	executorSandbox := "/frameworks/framework-1/executors/default-executor/runs/latest"
+	files.set(executorSandbox+"/stdout", "executor\n")
+	files.set(executorSandbox+"/tasks/task-1/stdout", "task\n")
+
+	if out := readTaskLog(t, NewTaskLogBuilder(cluster, "task-1")); out != "task\n" {
+		t.Errorf("expected the stdout of the task, got %q", out)
+	}
+}
+
+func TestTaskLogFollowAcrossRotation(t *testing.T) {
+	files, cluster, teardown := newTaskLogFixture(t, newTask("task-1", ""))
+	defer teardown()
+	stdout := commandSandbox + "/stdout"
+	files.set(stdout, "one\n")
+
+	l, err := NewTaskLogBuilder(cluster, "task-1").SetFollow(true).SetPollInterval(5 * time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := l.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(expected string) {
+		buf := make([]byte, len(expected))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != expected {
+			t.Errorf("expected %q, got %q", expected, buf)
+		}
+	}
+
+	read("one\n")
+	files.appendTo(stdout, "two\n")
+	read("two\n")
+
+	// Bytes written just before the rotation are read from the rotated file
+	files.appendTo(stdout, "three\n")
+	files.rename(stdout, stdout+".1")
+	files.set(stdout, "4\n")
+	read("three\n4\n")
+
+	done := make(chan error)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	r.Close()
+	select {
+	case err = <-done:
+		if err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt Read")
+	}
+	if _, err = r.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestTaskLogTaskNotFound(t *testing.T) {
+	_, cluster, teardown := newTaskLogFixture(t)
+	defer teardown()
+
+	l, err := NewTaskLogBuilder(cluster, "missing").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.Open(context.Background()); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestTaskLogBuilderRejectsOffsetWithTailLines(t *testing.T) {
+	if _, err := NewTaskLogBuilder(&Cluster{}, "task-1").SetOffset(1).SetTailLines(1).Build(); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,171 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// ErrFrameworkNotFound is returned by PlanTeardown when the master does not
+// know the framework or the framework has completed.
+var ErrFrameworkNotFound = errors.New("framework not found")
+
+// ErrTeardownNotConfirmed is returned by ConfirmTeardown when the token does
+// not match the current state of the framework, either because the token is
+// wrong or because the tasks of the framework changed since the plan was made.
+var ErrTeardownNotConfirmed = errors.New("teardown not confirmed: token does not match the framework")
+
+// TeardownPlan describes what tearing down a framework would stop. Show it to
+// the operator, then pass its Token to ConfirmTeardown.
+type TeardownPlan struct {
+	// Framework is the framework as reported by GetFrameworks.
+	Framework *mesos_v1_master.Response_GetFrameworks_Framework
+	// Tasks are the tasks of the framework that have not terminated.
+	Tasks []*mesos_v1.Task
+	// Token confirms the teardown of the framework with these tasks.
+	Token string
+}
+
+// PlanTeardown returns the TeardownPlan of the framework with the ID
+// frameworkID. Nothing is torn down.
+//
+// e.g.
+//
+// 	var plan TeardownPlan
+// 	plan, err = m.PlanTeardown(ctx, frameworkID)
+// 	fmt.Println(plan)
+// 	// Once the operator has typed the token back
+// 	err = m.ConfirmTeardown(ctx, frameworkID, token)
+func (m *Master) PlanTeardown(ctx context.Context, frameworkID *mesos_v1.FrameworkID) (plan TeardownPlan, err error) {
+	var response *mesos_v1_master.Response
+	response, err = m.GetFrameworks(ctx)
+	if err != nil {
+		return
+	}
+	for _, framework := range response.GetGetFrameworks().GetFrameworks() {
+		if framework.GetFrameworkInfo().GetId().GetValue() == frameworkID.GetValue() {
+			plan.Framework = framework
+		}
+	}
+	if plan.Framework == nil {
+		err = ErrFrameworkNotFound
+		return
+	}
+
+	response, err = m.GetTasks(ctx)
+	if err != nil {
+		return
+	}
+	var tasks []*mesos_v1.Task
+	tasks = append(tasks, response.GetGetTasks().GetPendingTasks()...)
+	tasks = append(tasks, response.GetGetTasks().GetTasks()...)
+	tasks = append(tasks, response.GetGetTasks().GetUnreachableTasks()...)
+	for _, task := range tasks {
+		if task.GetFrameworkId().GetValue() == frameworkID.GetValue() && !isTerminal(task.GetState()) {
+			plan.Tasks = append(plan.Tasks, task)
+		}
+	}
+	sort.Slice(plan.Tasks, func(i, j int) bool {
+		return plan.Tasks[i].GetTaskId().GetValue() < plan.Tasks[j].GetTaskId().GetValue()
+	})
+	plan.Token = teardownToken(frameworkID.GetValue(), plan.Tasks)
+	return
+}
+
+// ConfirmTeardown tears down the framework with the ID frameworkID if token is
+// the Token of a TeardownPlan of the framework in its current state. Otherwise
+// it returns ErrTeardownNotConfirmed and nothing is torn down.
+func (m *Master) ConfirmTeardown(ctx context.Context, frameworkID *mesos_v1.FrameworkID, token string) (err error) {
+	var plan TeardownPlan
+	plan, err = m.PlanTeardown(ctx, frameworkID)
+	if err != nil {
+		return
+	}
+	if token == "" || token != plan.Token {
+		err = ErrTeardownNotConfirmed
+		return
+	}
+	err = m.Teardown(ctx, &mesos_v1_master.Call_Teardown{FrameworkId: frameworkID})
+	return
+}
+
+// Resources returns the resources allocated to the framework, summed by
+// name. Only scalar resources are counted.
+func (p TeardownPlan) Resources() (resources map[string]float64) {
+	resources = map[string]float64{}
+	for _, resource := range p.Framework.GetAllocatedResources() {
+		if resource.GetType() == mesos_v1.Value_SCALAR {
+			resources[resource.GetName()] += resource.GetScalar().GetValue()
+		}
+	}
+	return
+}
+
+// String returns a summary of the plan for the operator.
+func (p TeardownPlan) String() string {
+	var buf bytes.Buffer
+	var info *mesos_v1.FrameworkInfo = p.Framework.GetFrameworkInfo()
+	fmt.Fprintf(&buf, "Framework %s (%s)\n", info.GetName(), info.GetId().GetValue())
+
+	var resources map[string]float64 = p.Resources()
+	var names []string = make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf.WriteString("Allocated resources:")
+	for _, name := range names {
+		fmt.Fprintf(&buf, " %s:%g", name, resources[name])
+	}
+	buf.WriteString("\n")
+
+	fmt.Fprintf(&buf, "Tasks (%d):\n", len(p.Tasks))
+	for _, task := range p.Tasks {
+		fmt.Fprintf(
+			&buf, "  %s %s on agent %s\n", task.GetTaskId().GetValue(), task.GetState(), task.GetAgentId().GetValue(),
+		)
+	}
+	fmt.Fprintf(&buf, "Confirmation token: %s\n", p.Token)
+	return buf.String()
+}
+
+// teardownToken returns a token that identifies a framework and its tasks, so
+// that a confirmation does not apply once the tasks have changed.
+func teardownToken(frameworkID string, tasks []*mesos_v1.Task) string {
+	var h hash.Hash = sha256.New()
+	fmt.Fprintf(h, "%s\n", frameworkID)
+	for _, task := range tasks {
+		fmt.Fprintf(h, "%s\n", task.GetTaskId().GetValue())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// fakeTeardownMaster is a master that knows framework-1 and its tasks.
+type fakeTeardownMaster struct {
+	*TestProtobufServer
+
+	mu    sync.Mutex
+	tasks []*mesos_v1.Task
+}
+
+func newTeardownMaster(tasks ...*mesos_v1.Task) (fake *fakeTeardownMaster) {
+	fake = &fakeTeardownMaster{TestProtobufServer: NewTestProtobufServer(MasterClient), tasks: tasks}
+	fake.HandleMasterCall(mesos_v1_master.Call_GET_FRAMEWORKS, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call,
+	) {
+		cpus := 1.5
+		writeResponse(rw, &mesos_v1_master.Response{
+			Type: mesos_v1_master.Response_GET_FRAMEWORKS.Enum(),
+			GetFrameworks: &mesos_v1_master.Response_GetFrameworks{
+				Frameworks: []*mesos_v1_master.Response_GetFrameworks_Framework{{
+					FrameworkInfo: &mesos_v1.FrameworkInfo{
+						Id:   &mesos_v1.FrameworkID{Value: proto.String("framework-1")},
+						Name: proto.String("marathon"),
+						User: proto.String("root"),
+					},
+					Active:    proto.Bool(true),
+					Connected: proto.Bool(true),
+					AllocatedResources: []*mesos_v1.Resource{
+						{Name: proto.String("cpus"), Type: mesos_v1.Value_SCALAR.Enum(), Scalar: &mesos_v1.Value_Scalar{Value: &cpus}},
+						{Name: proto.String("cpus"), Type: mesos_v1.Value_SCALAR.Enum(), Scalar: &mesos_v1.Value_Scalar{Value: &cpus}},
+					},
+				}},
+			},
+		})
+	}).HandleMasterCall(mesos_v1_master.Call_GET_TASKS, func(
+		rw http.ResponseWriter, req *http.Request, call *mesos_v1_master.Call,
+	) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		writeResponse(rw, &mesos_v1_master.Response{
+			Type:     mesos_v1_master.Response_GET_TASKS.Enum(),
+			GetTasks: &mesos_v1_master.Response_GetTasks{Tasks: fake.tasks},
+		})
+	}).Handle()
+	return
+}
+
+// tornDown returns the frameworks torn down, in order.
+func (f *fakeTeardownMaster) tornDown() (frameworkIDs []string) {
+	for _, call := range f.MasterCalls() {
+		if call.GetType() == mesos_v1_master.Call_TEARDOWN {
+			frameworkIDs = append(frameworkIDs, call.GetTeardown().GetFrameworkId().GetValue())
+		}
+	}
+	return
+}
+
+func TestPlanAndConfirmTeardown(t *testing.T) {
+	finished := newTask("task-3", "")
+	finished.State = mesos_v1.TaskState_TASK_FINISHED.Enum()
+	fake := newTeardownMaster(newTask("task-2", ""), newTask("task-1", ""), finished)
+	defer fake.Teardown()
+	m := fake.Master()
+	frameworkID := &mesos_v1.FrameworkID{Value: proto.String("framework-1")}
+
+	plan, err := m.PlanTeardown(context.Background(), frameworkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Tasks) != 2 || plan.Tasks[0].GetTaskId().GetValue() != "task-1" {
+		t.Errorf("expected task-1 and task-2, got %v", plan.Tasks)
+	}
+	if plan.Resources()["cpus"] != 3 {
+		t.Errorf("expected 3 cpus, got %v", plan.Resources())
+	}
+	summary := plan.String()
+	for _, expected := range []string{"marathon", "cpus:3", "task-1 TASK_RUNNING", plan.Token} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("expected %q in the summary %q", expected, summary)
+		}
+	}
+
+	if err = m.ConfirmTeardown(context.Background(), frameworkID, "wrong"); err != ErrTeardownNotConfirmed {
+		t.Errorf("expected ErrTeardownNotConfirmed, got %v", err)
+	}
+
+	// A task launched after the plan invalidates the token
+	fake.mu.Lock()
+	fake.tasks = append(fake.tasks, newTask("task-4", ""))
+	fake.mu.Unlock()
+	if err = m.ConfirmTeardown(context.Background(), frameworkID, plan.Token); err != ErrTeardownNotConfirmed {
+		t.Errorf("expected ErrTeardownNotConfirmed, got %v", err)
+	}
+	if tornDown := fake.tornDown(); len(tornDown) != 0 {
+		t.Errorf("expected no teardown, got %v", tornDown)
+	}
+
+	plan, err = m.PlanTeardown(context.Background(), frameworkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.ConfirmTeardown(context.Background(), frameworkID, plan.Token); err != nil {
+		t.Fatal(err)
+	}
+	if tornDown := fake.tornDown(); len(tornDown) != 1 || tornDown[0] != "framework-1" {
+		t.Errorf("expected framework-1 to be torn down, got %v", tornDown)
+	}
+}
+
+func TestPlanTeardownFrameworkNotFound(t *testing.T) {
+	fake := newTeardownMaster()
+	defer fake.Teardown()
+
+	_, err := fake.Master().PlanTeardown(context.Background(), &mesos_v1.FrameworkID{Value: proto.String("framework-2")})
+	if err != ErrFrameworkNotFound {
+		t.Errorf("expected ErrFrameworkNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsOptions holds the TLS settings given to a MasterBuilder or AgentBuilder.
+type tlsOptions struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+}
+
+// tlsFiles holds the CA bundle and client certificate loaded from the files in
+// tlsOptions. Each new connection checks whether the files were modified and
+// reloads them if so, which lets certificates be rotated on disk without
+// rebuilding the Master or Agent.
+//
+// The zero value is not usable; create a tlsFiles with newTLSFiles.
+type tlsFiles struct {
+	opts tlsOptions
+
+	mu          sync.Mutex
+	caModTime   time.Time
+	certModTime time.Time
+	keyModTime  time.Time
+	roots       *x509.CertPool
+	cert        *tls.Certificate
+}
+
+// newTLSFiles returns a pointer to a tlsFiles with the files in opts loaded.
+func newTLSFiles(opts tlsOptions) (f *tlsFiles, err error) {
+	if (opts.certFile == "") != (opts.keyFile == "") {
+		err = errors.New("both a client certificate and key are required")
+		return
+	}
+	f = &tlsFiles{opts: opts}
+	err = f.reload()
+	if err != nil {
+		f = nil
+	}
+	return
+}
+
+// config returns a *tls.Config for a connection to host that uses the loaded
+// files, reloading them first if they were modified.
+func (f *tlsFiles) config(host string) *tls.Config {
+	// A failed reload, such as one that catches the certificate written but
+	// not yet the key, leaves the previously loaded files in use.
+	f.reload()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var config *tls.Config = &tls.Config{ServerName: f.opts.serverName, RootCAs: f.roots}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	if f.cert != nil {
+		var cert *tls.Certificate = f.cert
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return config
+}
+
+// dialTLSContext returns a function for http.Transport.DialTLSContext that
+// dials with dial and performs the TLS handshake using the files as they are
+// at that moment.
+func (f *tlsFiles) dialTLSContext(
+	dial func(ctx context.Context, network string, addr string) (net.Conn, error),
+) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (conn net.Conn, err error) {
+		var host string
+		host, _, err = net.SplitHostPort(addr)
+		if err != nil {
+			return
+		}
+		var rawConn net.Conn
+		rawConn, err = dial(ctx, network, addr)
+		if err != nil {
+			return
+		}
+		var tlsConn *tls.Conn = tls.Client(rawConn, f.config(host))
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			rawConn.Close()
+			return
+		}
+		conn = tlsConn
+		return
+	}
+}
+
+// reload loads the files that were modified since they were last loaded. If
+// loading fails, the previously loaded files remain in use and loading is
+// tried again on the next call.
+func (f *tlsFiles) reload() (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.opts.caFile != "" {
+		var modTime time.Time
+		modTime, err = modifiedSince(f.opts.caFile, f.caModTime)
+		if err != nil {
+			return
+		}
+		if !modTime.IsZero() {
+			var pem []byte
+			pem, err = ioutil.ReadFile(f.opts.caFile)
+			if err != nil {
+				return
+			}
+			var roots *x509.CertPool = x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				err = fmt.Errorf("no certificates found in %s", f.opts.caFile)
+				return
+			}
+			f.roots = roots
+			f.caModTime = modTime
+		}
+	}
+
+	if f.opts.certFile != "" {
+		var certModTime, keyModTime time.Time
+		certModTime, err = modifiedSince(f.opts.certFile, f.certModTime)
+		if err != nil {
+			return
+		}
+		keyModTime, err = modifiedSince(f.opts.keyFile, f.keyModTime)
+		if err != nil {
+			return
+		}
+		if !certModTime.IsZero() || !keyModTime.IsZero() {
+			var cert tls.Certificate
+			cert, err = tls.LoadX509KeyPair(f.opts.certFile, f.opts.keyFile)
+			if err != nil {
+				return
+			}
+			f.cert = &cert
+			if !certModTime.IsZero() {
+				f.certModTime = certModTime
+			}
+			if !keyModTime.IsZero() {
+				f.keyModTime = keyModTime
+			}
+		}
+	}
+	return
+}
+
+// modifiedSince returns the modification time of the file at path if it is
+// other than since, and the zero time otherwise.
+func modifiedSince(path string, since time.Time) (modTime time.Time, err error) {
+	var info os.FileInfo
+	info, err = os.Stat(path)
+	if err != nil {
+		return
+	}
+	if !info.ModTime().Equal(since) {
+		modTime = info.ModTime()
+	}
+	return
+}
+
+// tlsTransport returns a copy of the given http.RoundTripper configured with
+// the TLS settings in files. A nil http.RoundTripper stands for
+// http.DefaultTransport. Only an *http.Transport can be configured.
+func tlsTransport(roundTripper http.RoundTripper, files *tlsFiles) (transport *http.Transport, err error) {
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	var ok bool
+	transport, ok = roundTripper.(*http.Transport)
+	if !ok {
+		err = fmt.Errorf("TLS options require an *http.Transport, got %T", roundTripper)
+		return
+	}
+	transport = transport.Clone()
+	var dial func(ctx context.Context, network string, addr string) (net.Conn, error) = transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialTLSContext = files.dialTLSContext(dial)
+	// TLSClientConfig is only used for connections through a proxy, which
+	// bypass DialTLSContext. Those use the files as they were at build time.
+	transport.TLSClientConfig = files.config("")
+	return
+}
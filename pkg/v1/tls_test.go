@@ -0,0 +1,354 @@
+package v1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// testCA is a certificate authority that issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mesops test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate and key for the given DNS names,
+// valid for 127.0.0.1 as well.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage, dnsNames ...string) (certPEM []byte, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "mesops test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+	}
+	if len(dnsNames) == 0 {
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return
+}
+
+// writeFile writes data to the file at path and moves its modification time
+// forward, so that a rewrite within the file system's time resolution is
+// still noticed.
+func writeFile(t *testing.T, path string, data []byte) {
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if modTime.IsZero() {
+		return
+	}
+	modTime = modTime.Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTLSServer returns a started server that answers every request with a
+// healthy GET_HEALTH response using the certificate returned by getCert. If
+// clientCAs is not nil, the server requires a client certificate issued by
+// it.
+func newTLSServer(t *testing.T, getCert func() *tls.Certificate, clientCAs *x509.CertPool) *httptest.Server {
+	output := healthyOutput(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write(output)
+	}))
+	// httptest sets a certificate of its own, which GetCertificate would not
+	// override for clients that send no SNI, such as those dialing an IP.
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{Certificates: []tls.Certificate{*getCert()}}
+			if clientCAs != nil {
+				config.ClientCAs = clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+	// Rejected handshakes are expected, keep them out of the test output
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	return server
+}
+
+func staticCert(t *testing.T, certPEM []byte, keyPEM []byte) func() *tls.Certificate {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() *tls.Certificate { return &cert }
+}
+
+func TestMasterMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, x509.ExtKeyUsageClientAuth, "client")
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), clientCert)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), clientKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := newTLSServer(t, staticCert(t, serverCert, serverKey), clientCAs)
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).
+		SetCACertFile(filepath.Join(dir, "ca.pem")).
+		SetClientCertFiles(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+
+	// Without a client certificate, the server rejects the handshake
+	m, err = NewMasterBuilder(server.URL).SetCACertFile(filepath.Join(dir, "ca.pem")).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = m.sendSimpleCall(context.Background(), mesos_v1_master.Call_GET_HEALTH); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestAgentRejectsUnknownCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCert, serverKey := newTestCA(t).issue(t, x509.ExtKeyUsageServerAuth)
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+
+	server := newTLSServer(t, staticCert(t, serverCert, serverKey), nil)
+	defer server.Close()
+
+	a, err := NewAgentBuilder(server.URL).SetCACertFile(filepath.Join(dir, "ca.pem")).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = a.sendSimpleCall(context.Background(), mesos_v1_agent.Call_GET_HEALTH); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestAgentServerName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, x509.ExtKeyUsageServerAuth, "agent.mesos")
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+
+	server := newTLSServer(t, staticCert(t, serverCert, serverKey), nil)
+	defer server.Close()
+
+	a, err := NewAgentBuilder(server.URL).
+		SetCACertFile(filepath.Join(dir, "ca.pem")).
+		SetServerName("agent.mesos").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.GetHealth(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMasterReloadsRotatedCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldCA := newTestCA(t)
+	newCA := newTestCA(t)
+	writeFile(t, filepath.Join(dir, "ca.pem"), oldCA.pem)
+
+	var mu sync.Mutex
+	oldCert, oldKey := oldCA.issue(t, x509.ExtKeyUsageServerAuth)
+	newCert, newKey := newCA.issue(t, x509.ExtKeyUsageServerAuth)
+	current := staticCert(t, oldCert, oldKey)
+	server := newTLSServer(t, func() *tls.Certificate {
+		mu.Lock()
+		defer mu.Unlock()
+		return current()
+	}, nil)
+	defer server.Close()
+
+	m, err := NewMasterBuilder(server.URL).SetCACertFile(filepath.Join(dir, "ca.pem")).SetMaxRetries(0).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the server certificate to one issued by the new CA and force a
+	// new handshake.
+	mu.Lock()
+	current = staticCert(t, newCert, newKey)
+	mu.Unlock()
+	server.CloseClientConnections()
+	if _, _, err = m.sendSimpleCall(context.Background(), mesos_v1_master.Call_GET_HEALTH); err == nil {
+		t.Fatal("expected an error before the CA bundle is rotated, got nil")
+	}
+
+	writeFile(t, filepath.Join(dir, "ca.pem"), newCA.pem)
+	if _, err = m.GetHealth(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMasterNewAgentBuilderInheritsTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mesops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	masterCert, masterKey := ca.issue(t, x509.ExtKeyUsageServerAuth, "master.mesos")
+	agentCert, agentKey := ca.issue(t, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, x509.ExtKeyUsageClientAuth, "client")
+	writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	writeFile(t, filepath.Join(dir, "client.pem"), clientCert)
+	writeFile(t, filepath.Join(dir, "client-key.pem"), clientKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	master := newTLSServer(t, staticCert(t, masterCert, masterKey), clientCAs)
+	defer master.Close()
+	agent := newTLSServer(t, staticCert(t, agentCert, agentKey), clientCAs)
+	defer agent.Close()
+
+	m, err := NewMasterBuilder(master.URL).
+		SetCACertFile(filepath.Join(dir, "ca.pem")).
+		SetClientCertFiles(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")).
+		SetServerName("master.mesos").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(agent.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname := u.Hostname()
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port32 := int32(port)
+	a, err := m.NewAgentBuilder(&mesos_v1.AgentInfo{Hostname: &hostname, Port: &port32}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.baseURL.Scheme != "https" {
+		t.Errorf("expected https, got %s", a.baseURL.Scheme)
+	}
+	data, err := a.GetHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.GetGetHealth().GetHealthy() {
+		t.Error("expected true, got false")
+	}
+}
+
+func TestTLSRequiresCertAndKey(t *testing.T) {
+	_, err := NewAgentBuilder("https://127.0.0.1:5051").SetClientCertFiles("client.pem", "").Build()
+	if err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
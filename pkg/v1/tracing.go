@@ -0,0 +1,106 @@
+// MIT License
+//
+// Copyright (c) [2017-2018] [Demitri Swan]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by mesops.
+const tracerName string = "github.com/miroswan/mesops/pkg/v1"
+
+// Attribute keys set on the spans of calls. The HTTP and URL keys follow the
+// OpenTelemetry semantic conventions.
+const (
+	attributeCallType     attribute.Key = "mesos.call.type"
+	attributeRetryCount   attribute.Key = "mesos.retry_count"
+	attributeURL          attribute.Key = "url.full"
+	attributeStatusCode   attribute.Key = "http.response.status_code"
+	attributeResponseSize attribute.Key = "http.response.body.size"
+	attributeBackoff      attribute.Key = "mesos.backoff"
+	attributeError        attribute.Key = "error.message"
+)
+
+// startSpan starts the span of a call, named after its type, e.g. GET_HEALTH.
+// If tracing is not configured, it returns ctx and a nil trace.Span.
+func (c *client) startSpan(ctx context.Context, callType string) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	return c.tracer.Start(ctx, callType,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributeCallType.String(callType)),
+	)
+}
+
+// startStreamSpan starts a span covering a long lived stream, such as the
+// subscription kept by a Subscriber across reconnects. If tracing is not
+// configured, it returns ctx and a nil trace.Span.
+func (c *client) startStreamSpan(ctx context.Context, callType string) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+	return c.tracer.Start(ctx, callType+" stream", trace.WithAttributes(attributeCallType.String(callType)))
+}
+
+// addEvent adds an event to span. A nil span is ignored.
+func addEvent(span trace.Span, name string, attributes ...attribute.KeyValue) {
+	if span == nil {
+		return
+	}
+	span.AddEvent(name, trace.WithAttributes(attributes...))
+}
+
+// endSpan records err, if any, on span and ends it. A nil span is ignored.
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// span returns the span of the call made with ctx, or nil if tracing is not
+// configured. It keeps mesops from annotating a span of the caller.
+func (c *client) span(ctx context.Context) trace.Span {
+	if c.tracer == nil {
+		return nil
+	}
+	return trace.SpanFromContext(ctx)
+}
+
+// injectTraceContext adds the trace context of ctx to the headers of req.
+func (c *client) injectTraceContext(ctx context.Context, req *http.Request) {
+	if c.tracer == nil {
+		return
+	}
+	c.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
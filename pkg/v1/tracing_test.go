@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mesos/go-proto/mesos/v1/agent"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracerProvider returns a TracerProvider that exports each span to the
+// returned in-memory exporter as soon as it ends.
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// spanAttribute returns the value of the attribute key of span.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMasterTracesCalls(t *testing.T) {
+	output := healthyOutput(t)
+	var traceparent atomic.Value
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// The first connection is dropped to force a retry
+		if atomic.AddInt32(&hits, 1) == 1 {
+			conn, _, _ := rw.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		traceparent.Store(req.Header.Get("Traceparent"))
+		rw.Write(output)
+	}))
+	defer server.Close()
+
+	tp, exporter := newTracerProvider()
+	m, err := NewMasterBuilder(server.URL).
+		SetTracerProvider(tp).
+		SetPropagator(propagation.TraceContext{}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.GetHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET_HEALTH" {
+		t.Errorf("expected GET_HEALTH, got %s", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("expected a client span, got %s", span.SpanKind)
+	}
+	if v := spanAttribute(span, attributeURL).AsString(); v != server.URL+"/api/v1" {
+		t.Errorf("expected %s/api/v1, got %s", server.URL, v)
+	}
+	if v := spanAttribute(span, attributeStatusCode).AsInt64(); v != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, v)
+	}
+	if v := spanAttribute(span, attributeRetryCount).AsInt64(); v != 1 {
+		t.Errorf("expected 1 retry, got %d", v)
+	}
+	if v := spanAttribute(span, attributeResponseSize).AsInt64(); v != int64(len(output)) {
+		t.Errorf("expected %d bytes, got %d", len(output), v)
+	}
+
+	// The trace context reached the server
+	carrier := propagation.HeaderCarrier(http.Header{"Traceparent": {traceparent.Load().(string)}})
+	remote := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if remote.TraceID() != span.SpanContext.TraceID() || remote.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("expected trace context %s, got %s", span.SpanContext.TraceID(), traceparent.Load())
+	}
+}
+
+func TestAgentTracesFailedCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tp, exporter := newTracerProvider()
+	a, err := NewAgentBuilder(server.URL).SetTracerProvider(tp).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = a.sendSimpleCall(context.Background(), mesos_v1_agent.Call_GET_STATE)
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name != "GET_STATE" {
+		t.Errorf("expected GET_STATE, got %s", spans[0].Name)
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("expected an error status, got %s", spans[0].Status.Code)
+	}
+	if v := spanAttribute(spans[0], attributeStatusCode).AsInt64(); v != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, got %d", http.StatusServiceUnavailable, v)
+	}
+}
+
+func TestSubscriberTracesReconnects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		writeRecordioEvents(t, rw, subscribedEvent())
+	}))
+	defer server.Close()
+
+	tp, exporter := newTracerProvider()
+	m, err := NewMasterBuilder(server.URL).SetTracerProvider(tp).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSubscriberBuilder(m).SetMinBackoff(time.Millisecond).SetMaxBackoff(time.Millisecond).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	es := make(EventStream)
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.Subscribe(ctx, es) }()
+	// SUBSCRIBED, then EventResync and SUBSCRIBED after the reconnect
+	for i := 0; i < 3; i++ {
+		select {
+		case <-es:
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+	cancel()
+	<-errChan
+
+	var stream *tracetest.SpanStub
+	var calls int
+	for _, span := range exporter.GetSpans() {
+		span := span
+		switch span.Name {
+		case "SUBSCRIBE stream":
+			stream = &span
+		case "SUBSCRIBE":
+			calls++
+		}
+	}
+	if stream == nil {
+		t.Fatal("expected a SUBSCRIBE stream span")
+	}
+	if calls < 2 {
+		t.Errorf("expected at least 2 SUBSCRIBE spans, got %d", calls)
+	}
+	var names []string
+	for _, event := range stream.Events {
+		names = append(names, event.Name)
+	}
+	if len(names) < 3 || names[0] != "subscribed" || names[1] != "reconnect" || names[2] != "subscribed" {
+		t.Errorf("expected subscribed, reconnect, subscribed events, got %q", names)
+	}
+}
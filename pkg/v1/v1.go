@@ -25,10 +25,10 @@ package v1
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -39,10 +39,14 @@ import (
 	"github.com/mesos/go-proto/mesos/v1/agent"
 	"github.com/mesos/go-proto/mesos/v1/master"
 	"github.com/miroswan/mesops/pkg"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MasterAPI interface {
 	GetExecutors(ctx context.Context) (response *mesos_v1_master.Response, err error)
+	GetOperations(ctx context.Context) (response *mesos_v1_master.Response, err error)
 	ListFiles(ctx context.Context, call *mesos_v1_master.Call_ListFiles) (response *mesos_v1_master.Response, err error)
 	ReadFile(ctx context.Context, call *mesos_v1_master.Call_ReadFile) (response *mesos_v1_master.Response, err error)
 	GetFlags(ctx context.Context) (response *mesos_v1_master.Response, err error)
@@ -52,7 +56,7 @@ type MasterAPI interface {
 	SetLoggingLevel(ctx context.Context, call *mesos_v1_master.Call_SetLoggingLevel) (err error)
 	GetMaintenanceStatus(ctx context.Context) (response *mesos_v1_master.Response, err error)
 	GetMaintenanceSchedule(ctx context.Context) (response *mesos_v1_master.Response, err error)
-	UpdateMaintenanceSchedule(ctx context.Context, call *mesos_v1_master.Call_UpdateMaintenanceSchedule)
+	UpdateMaintenanceSchedule(ctx context.Context, call *mesos_v1_master.Call_UpdateMaintenanceSchedule) (err error)
 	StartMaintenance(ctx context.Context, call *mesos_v1_master.Call_StartMaintenance) (err error)
 	StopMaintenance(ctx context.Context, call *mesos_v1_master.Call_StopMaintenance) (err error)
 	GetMaster(ctx context.Context) (response *mesos_v1_master.Response, err error)
@@ -69,20 +73,30 @@ type MasterAPI interface {
 	GetVersion(ctx context.Context) (response *mesos_v1_master.Response, err error)
 	CreateVolumes(ctx context.Context, call *mesos_v1_master.Call_CreateVolumes) (err error)
 	DestroyVolumes(ctx context.Context, call *mesos_v1_master.Call_DestroyVolumes) (err error)
+	GrowVolume(ctx context.Context, call *mesos_v1_master.Call_GrowVolume) (err error)
+	ShrinkVolume(ctx context.Context, call *mesos_v1_master.Call_ShrinkVolume) (err error)
 	GetWeights(ctx context.Context) (response *mesos_v1_master.Response, err error)
+	UpdateWeights(ctx context.Context, call *mesos_v1_master.Call_UpdateWeights) (err error)
+	Teardown(ctx context.Context, call *mesos_v1_master.Call_Teardown) (err error)
 	MarkAgentGone(ctx context.Context, call *mesos_v1_master.Call_MarkAgentGone) (
 		response *mesos_v1_master.Response, err error,
 	)
+	DrainAgent(ctx context.Context, call *mesos_v1_master.Call_DrainAgent) (err error)
+	DeactivateAgent(ctx context.Context, call *mesos_v1_master.Call_DeactivateAgent) (err error)
+	ReactivateAgent(ctx context.Context, call *mesos_v1_master.Call_ReactivateAgent) (err error)
 }
 
 type AgentAPI interface {
-	GetAgents(ctx context.Context) (response *mesos_v1_master.Response, err error)
 	GetContainers(ctx context.Context) (response *mesos_v1_agent.Response, err error)
 	LaunchContainer(ctx context.Context, call *mesos_v1_agent.Call_LaunchContainer) (err error)
+	WaitContainer(ctx context.Context, call *mesos_v1_agent.Call_WaitContainer) (response *mesos_v1_agent.Response, err error)
+	KillContainer(ctx context.Context, call *mesos_v1_agent.Call_KillContainer) (err error)
+	RemoveContainer(ctx context.Context, call *mesos_v1_agent.Call_RemoveContainer) (err error)
 	LaunchNestedContainer(ctx context.Context, call *mesos_v1_agent.Call_LaunchNestedContainer) (err error)
-	WaitNestedContainer(ctx context.Context, call *mesos_v1_agent.Call_WaitNestedContainer)
+	WaitNestedContainer(ctx context.Context, call *mesos_v1_agent.Call_WaitNestedContainer) (response *mesos_v1_agent.Response, err error)
 	KillNestedContainer(ctx context.Context, call *mesos_v1_agent.Call_KillNestedContainer) (err error)
 	GetExecutors(ctx context.Context) (response *mesos_v1_agent.Response, err error)
+	GetOperations(ctx context.Context) (response *mesos_v1_agent.Response, err error)
 	ListFiles(ctx context.Context, call *mesos_v1_agent.Call_ListFiles) (response *mesos_v1_agent.Response, err error)
 	ReadFile(ctx context.Context, call *mesos_v1_agent.Call_ReadFile) (response *mesos_v1_agent.Response, err error)
 	GetFlags(ctx context.Context) (response *mesos_v1_agent.Response, err error)
@@ -104,17 +118,11 @@ type AgentAPI interface {
 	RemoveNestedContainer(ctx context.Context, call *mesos_v1_agent.Call_RemoveNestedContainer) (err error)
 }
 
-// HTTPError is a custom error type for HTTP errors outside of the 200 range.
-type HTTPError struct {
-	statusCode int
-	msg        string
-}
+// The Master implements the MasterAPI.
+var _ MasterAPI = &Master{}
 
-// Error implements the error interface for HTTPError, printing status_code and
-// msg information.
-func (e HTTPError) Error() string {
-	return fmt.Sprintf("request failed: status_code: %d msg: %s", e.statusCode, e.msg)
-}
+// The Agent implements the AgentAPI.
+var _ AgentAPI = &Agent{}
 
 // IPv4toInt64 parses a string in the form of an IPv4 address and returns an
 // int64
@@ -161,19 +169,48 @@ type client struct {
 	userAgent  *string
 	baseURL    *url.URL
 	maxRetries *int
+	// retryPolicy decides whether a failed call is sent again and attemptHook,
+	// if set, is called with the outcome of every attempt.
+	retryPolicy RetryPolicy
+	attemptHook func(attempt Attempt)
+	// interceptors run around every call, ending with invoke. The chain is
+	// built into invoker.
+	interceptors []Interceptor
+	invoker      Invoker
+	// tracerProvider, if set, enables tracing of calls with tracer. The
+	// trace context is propagated with propagator.
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+	propagator     propagation.TextMapPropagator
+	// metrics, if set, records the calls made by the client, which is either
+	// a master or agent client as given by target.
+	metrics *ClientMetrics
+	target  string
+	// serverURLs holds every configured endpoint. For a Master, these are the
+	// members of the HA set and leader tracks which of them is leading.
+	serverURLs []*url.URL
+	leader     *leader
+
+	encoding           Encoding
+	basicAuth          *basicAuth
+	credentialProvider CredentialProvider
+	tlsOptions         *tlsOptions
+	// baseHTTPClient is the http.Client given to the builder, before it was
+	// configured for redirects and TLS.
+	baseHTTPClient *http.Client
 }
 
 // clientBuilder is a builder that constructs a pointer to a client. In most
 // cases, you'll want a MasterBuilder or AgentBuilder instead.
 type clientBuilder struct {
 	*client
-	serverURL *string
+	serverURLs []string
 }
 
 // clientBuilder hold a pointer to a client and has setters for optional
 // arguments. Its Build method returns the pointer to the constructed client
-func newClientBuilder(serverURL string) *clientBuilder {
-	return &clientBuilder{client: &client{}, serverURL: &serverURL}
+func newClientBuilder(serverURLs ...string) *clientBuilder {
+	return &clientBuilder{client: &client{}, serverURLs: serverURLs}
 }
 
 // setServerURL ... (see MasterBuilder and AgentBuilder)
@@ -188,37 +225,196 @@ func (b *clientBuilder) setHTTPclient(httpclient *http.Client) *clientBuilder {
 	return b
 }
 
+// setEncoding ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setEncoding(encoding Encoding) *clientBuilder {
+	b.client.encoding = encoding
+	return b
+}
+
+// setBasicAuth ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setBasicAuth(principal string, secret string) *clientBuilder {
+	b.client.basicAuth = &basicAuth{principal: principal, secret: secret}
+	return b
+}
+
+// setCredentialProvider ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setCredentialProvider(credentialProvider CredentialProvider) *clientBuilder {
+	b.client.credentialProvider = credentialProvider
+	return b
+}
+
+// setCACertFile ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setCACertFile(caFile string) *clientBuilder {
+	b.tls().caFile = caFile
+	return b
+}
+
+// setClientCertFiles ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setClientCertFiles(certFile string, keyFile string) *clientBuilder {
+	b.tls().certFile = certFile
+	b.tls().keyFile = keyFile
+	return b
+}
+
+// setServerName ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setServerName(serverName string) *clientBuilder {
+	b.tls().serverName = serverName
+	return b
+}
+
+// tls returns the tlsOptions of the client, creating them if not set.
+func (b *clientBuilder) tls() *tlsOptions {
+	if b.client.tlsOptions == nil {
+		b.client.tlsOptions = &tlsOptions{}
+	}
+	return b.client.tlsOptions
+}
+
+// setRetryPolicy ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setRetryPolicy(retryPolicy RetryPolicy) *clientBuilder {
+	b.client.retryPolicy = retryPolicy
+	return b
+}
+
+// setAttemptHook ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setAttemptHook(attemptHook func(attempt Attempt)) *clientBuilder {
+	b.client.attemptHook = attemptHook
+	return b
+}
+
+// setInterceptors ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setInterceptors(interceptors ...Interceptor) *clientBuilder {
+	b.client.interceptors = interceptors
+	return b
+}
+
+// setTracerProvider ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setTracerProvider(tracerProvider trace.TracerProvider) *clientBuilder {
+	b.client.tracerProvider = tracerProvider
+	return b
+}
+
+// setPropagator ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setPropagator(propagator propagation.TextMapPropagator) *clientBuilder {
+	b.client.propagator = propagator
+	return b
+}
+
+// setClientMetrics ... (see MasterBuilder and AgentBuilder)
+func (b *clientBuilder) setClientMetrics(metrics *ClientMetrics) *clientBuilder {
+	b.client.metrics = metrics
+	return b
+}
+
 // setMaxRetries ... (see MasterBuilder and AgentBuilder)
 func (b *clientBuilder) setMaxRetries(maxRetries int) *clientBuilder {
 	b.client.maxRetries = &maxRetries
 	return b
 }
 
+// inherit returns a pointer to a clientBuilder for serverURL that carries over
+// the settings of c, such as the HTTP client, encoding, retries, interceptors,
+// tracing, metrics, credentials and TLS settings of a Master handed down to
+// the Agents found through it. The TLS server name is not carried over, since
+// it names the master.
+func (c *client) inherit(serverURL string) *clientBuilder {
+	var b *clientBuilder = newClientBuilder(serverURL)
+	b.setHTTPclient(c.baseHTTPClient)
+	b.setMaxRetries(*c.maxRetries)
+	b.setRetryPolicy(c.retryPolicy)
+	b.setAttemptHook(c.attemptHook)
+	b.setInterceptors(c.interceptors...)
+	b.setTracerProvider(c.tracerProvider)
+	b.setPropagator(c.propagator)
+	b.setClientMetrics(c.metrics)
+	b.setEncoding(c.encoding)
+	b.client.basicAuth = c.basicAuth
+	b.client.credentialProvider = c.credentialProvider
+	if c.tlsOptions != nil {
+		var opts tlsOptions = *c.tlsOptions
+		opts.serverName = ""
+		b.client.tlsOptions = &opts
+	}
+	return b
+}
+
 // build returns a pointer to a constructed client
 func (b *clientBuilder) build() (client *client, err error) {
-	// Append api path prefix if not present
-	if !strings.HasSuffix(*b.serverURL, "api/v1") {
-		if !strings.HasSuffix(*b.serverURL, "/") {
-			*b.serverURL += "/api/v1"
-		} else {
-			*b.serverURL += "api/v1"
+	if len(b.serverURLs) == 0 {
+		err = errors.New("at least one server URL is required")
+		return
+	}
+	var serverURLs []*url.URL = make([]*url.URL, 0, len(b.serverURLs))
+	for _, serverURL := range b.serverURLs {
+		// Append api path prefix if not present
+		if !strings.HasSuffix(serverURL, "api/v1") {
+			if !strings.HasSuffix(serverURL, "/") {
+				serverURL += "/api/v1"
+			} else {
+				serverURL += "api/v1"
+			}
 		}
+		var u *url.URL
+		u, err = url.Parse(serverURL)
+		if err != nil {
+			return
+		}
+		serverURLs = append(serverURLs, u)
 	}
-	var u *url.URL
-	u, err = url.Parse(*b.serverURL)
-	if err != nil {
+	if b.client.encoding != EncodingProtobuf && b.client.encoding != EncodingJSON {
+		err = fmt.Errorf("unknown encoding: %d", b.client.encoding)
+		return
+	}
+	if b.client.basicAuth != nil && b.client.credentialProvider != nil {
+		err = errors.New("basic auth and a credential provider cannot be used together")
 		return
 	}
-	b.setServerURL(u)
+	b.setServerURL(serverURLs[0])
+	b.client.serverURLs = serverURLs
+	b.client.leader = newLeader(serverURLs)
 
 	// Set a sane default http.Client if not set
 	if b.client.httpclient == nil {
 		b.setHTTPclient(http.DefaultClient)
 	}
+	b.client.baseHTTPClient = b.client.httpclient
+	// Redirects are followed by doProto so that the leader can be cached. Copy
+	// the http.Client so that the caller's client is left untouched.
+	var httpclient http.Client = *b.client.httpclient
+	httpclient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	if b.client.tlsOptions != nil {
+		var files *tlsFiles
+		files, err = newTLSFiles(*b.client.tlsOptions)
+		if err != nil {
+			return
+		}
+		httpclient.Transport, err = tlsTransport(httpclient.Transport, files)
+		if err != nil {
+			return
+		}
+	}
+	b.setHTTPclient(&httpclient)
+
 	// Set maxRetries if not set
 	if b.client.maxRetries == nil {
 		b.setMaxRetries(10)
 	}
+	// Set retryPolicy if not set
+	if b.client.retryPolicy == nil {
+		b.setRetryPolicy(IdempotentOnly(&binaryExponentialBackoff{maxRetries: *b.client.maxRetries}))
+	}
+
+	b.client.invoker = chainInterceptors(b.client.interceptors, b.client.invoke)
+
+	// Enable tracing if a TracerProvider is set
+	if b.client.tracerProvider != nil {
+		b.client.tracer = b.client.tracerProvider.Tracer(tracerName, trace.WithInstrumentationVersion(pkg.Version))
+		if b.client.propagator == nil {
+			b.setPropagator(otel.GetTextMapPropagator())
+		}
+	}
 
 	// Set UserAgent
 	var userAgent string = fmt.Sprintf("mesops/%s", pkg.Version)
@@ -227,83 +423,149 @@ func (b *clientBuilder) build() (client *client, err error) {
 	return
 }
 
-func (c *client) doProtoWrapper(ctx context.Context, body io.Reader, pb proto.Message) (res *http.Response, err error) {
-	var r []int = make([]int, *c.maxRetries+1) // Setup range for retries
-	var start time.Time                        // for generating the round trip time
-	var elapsed time.Duration
-	var backoff *binaryExponentialBackoff = &binaryExponentialBackoff{}
-	var errChan chan error = make(chan error)
-	go func() {
-		var finalErr error
-		for count := range r {
+// doProtoWrapper sends the call with doProto, sending it again for as long as
+// the RetryPolicy says so.
+func (c *client) doProtoWrapper(
+	ctx context.Context, callType string, body []byte, pb proto.Message,
+) (res *http.Response, err error) {
+	var start time.Time = time.Now()
+	var rtt time.Duration
+	for number := 1; ; number++ {
+		var attemptStart time.Time = time.Now()
+		res, err = c.doProto(ctx, body, pb)
+		if httpError, ok := err.(HTTPError); ok {
+			httpError.CallType = callType
+			httpError.Attempts = number
+			err = httpError
+		}
+		var attempt Attempt = Attempt{
+			CallType: callType,
+			Number:   number,
+			Err:      err,
+			Duration: time.Since(attemptStart),
+			Elapsed:  time.Since(start),
+		}
+		// The duration of the first attempt estimates the round trip time
+		if number == 1 {
+			rtt = attempt.Duration
+		}
+		attempt.rtt = rtt
+		if span := c.span(ctx); span != nil {
+			span.SetAttributes(attributeRetryCount.Int(number - 1))
+		}
+		if c.attemptHook != nil {
+			c.attemptHook(attempt)
+		}
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
 
-			// If it is not the first request, then wait
-			if count != 0 {
-				backoff.wait(count)
-			}
-			// If the round trip time is not set, then set the start time
-			if backoff.rtt == nil {
-				start = time.Now()
-			}
-			res, err = c.doProto(ctx, body, pb)
-			// If the round trip time is not set, then calculate the elapsed time and
-			// set it to the round trip time. We will use this in later iterations to
-			// allow the backoff to wait for the the correct interval.
-			if backoff.rtt == nil {
-				elapsed = time.Since(start)
-				backoff.rtt = &elapsed
-			}
-			// If there was no error, then return. If there was an HTTPError then do not
-			// retry. Only retry on other errors.
-			if err == nil {
-				errChan <- err
-				return
-			} else {
-				var ok bool
-				var httpError HTTPError
-				if httpError, ok = err.(HTTPError); ok {
-					errChan <- httpError
-					return
-				} else {
-					finalErr = err
-				}
+		var retry bool
+		var wait time.Duration
+		retry, wait = c.retryPolicy.Retry(attempt)
+		if !retry {
+			// A call that was not resent, such as a mutating call refused by
+			// IdempotentOnly, may have been applied, so it is not reported
+			// as exhausted
+			if _, ok := err.(HTTPError); !ok && number > 1 {
+				err = RetriesExhaustedError{CallType: callType, Attempts: number, Err: err}
 			}
+			return
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+		c.metrics.observeRetry(callType, c.target)
+		var timer *time.Timer = time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return
+		case <-timer.C:
 		}
-		errChan <- fmt.Errorf("exceeded %d retries: %s", *c.maxRetries, finalErr)
-		return
-	}()
-	select {
-	case <-ctx.Done():
-		err = ctx.Err()
-		return
-	case err = <-errChan:
-		return
 	}
 }
 
-func (c *client) doProto(ctx context.Context, body io.Reader, pb proto.Message) (httpRes *http.Response, err error) {
-	var req *http.Request
-	req, err = http.NewRequest(http.MethodPost, c.baseURL.String(), body)
-	if err != nil {
-		return
-	}
-	req.Header.Set("Content-Type", "application/x-protobuf")
-	req.Header.Set("Accept", "application/x-protobuf")
-	req.Header.Set("User-Agent", *c.userAgent)
+func (c *client) doProto(ctx context.Context, body []byte, pb proto.Message) (httpRes *http.Response, err error) {
+	var endpoint *url.URL = c.leader.get()
+	var redirects int
+	var refreshed bool
+	for {
+		var req *http.Request
+		// The body is read anew for each request so that it can be resent after
+		// a redirect.
+		req, err = http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", c.encoding.contentType())
+		req.Header.Set("Accept", c.encoding.contentType())
+		if streamingResponse(ctx) {
+			req.Header.Set("Accept", recordioContentType)
+			req.Header.Set("Message-Accept", c.encoding.contentType())
+		}
+		err = c.setHeaders(ctx, req)
+		if err != nil {
+			return
+		}
 
-	req = req.WithContext(ctx)
+		req = req.WithContext(ctx)
 
-	defer req.Body.Close()
+		httpRes, err = c.httpclient.Do(req)
+		if span := c.span(ctx); span != nil {
+			span.SetAttributes(attributeURL.String(endpoint.String()))
+			if err == nil {
+				span.SetAttributes(attributeStatusCode.Int(httpRes.StatusCode))
+			}
+		}
+		if err != nil {
+			// The endpoint could not be reached. Forget it so that the next
+			// attempt looks for the leader elsewhere.
+			c.leader.forget(endpoint)
+			return
+		}
 
-	httpRes, err = c.httpclient.Do(req)
-	if err != nil {
-		return
+		// The token may have expired. Refresh it and try once more.
+		if httpRes.StatusCode == http.StatusUnauthorized && c.credentialProvider != nil && !refreshed {
+			httpRes.Body.Close()
+			refreshed = true
+			err = c.credentialProvider.Refresh(ctx)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		// A non-leading master redirects to the leading master. Follow the
+		// redirect and remember where it led.
+		if httpRes.StatusCode != http.StatusTemporaryRedirect || redirects >= maxRedirects {
+			break
+		}
+		var location string = httpRes.Header.Get("Location")
+		if location == "" {
+			break
+		}
+		httpRes.Body.Close()
+		endpoint, err = endpoint.Parse(location)
+		if err != nil {
+			return
+		}
+		c.leader.set(endpoint)
+		redirects++
 	}
 
 	if httpRes.StatusCode > 299 || httpRes.StatusCode < 200 {
 		var msg []byte
 		msg, _ = ioutil.ReadAll(httpRes.Body)
-		err = HTTPError{statusCode: httpRes.StatusCode, msg: string(msg)}
+		if span := c.span(ctx); span != nil {
+			span.SetAttributes(attributeResponseSize.Int(len(msg)))
+		}
+		err = HTTPError{StatusCode: httpRes.StatusCode, Body: string(msg)}
 		return
 	}
 
@@ -313,7 +575,10 @@ func (c *client) doProto(ctx context.Context, body io.Reader, pb proto.Message)
 		if err != nil {
 			return
 		}
-		err = proto.Unmarshal(j, pb)
+		if span := c.span(ctx); span != nil {
+			span.SetAttributes(attributeResponseSize.Int(len(j)))
+		}
+		err = c.encoding.unmarshal(j, pb)
 		if err != nil {
 			return
 		}
@@ -321,29 +586,121 @@ func (c *client) doProto(ctx context.Context, body io.Reader, pb proto.Message)
 	return
 }
 
+// setHeaders sets the headers shared by all requests: the user agent, the
+// trace context, the headers added with WithRequestHeader and the credentials.
+func (c *client) setHeaders(ctx context.Context, req *http.Request) (err error) {
+	req.Header.Set("User-Agent", *c.userAgent)
+	c.injectTraceContext(ctx, req)
+	for key, values := range requestHeader(ctx) {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	err = c.authorize(ctx, req)
+	return
+}
+
+// recordioContentType is the media type of RecordIO streams. The media type
+// of the records is sent in the Message-Content-Type and Message-Accept
+// headers.
+const recordioContentType string = "application/recordio"
+
+// streamingResponseKey is the context key set by withStreamingResponse.
+type streamingResponseKey struct{}
+
+// withStreamingResponse returns a copy of ctx whose calls ask for a RecordIO
+// stream in response, as LAUNCH_NESTED_CONTAINER_SESSION and
+// ATTACH_CONTAINER_OUTPUT require.
+func withStreamingResponse(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingResponseKey{}, true)
+}
+
+// streamingResponse reports whether ctx was returned by withStreamingResponse.
+func streamingResponse(ctx context.Context) bool {
+	var streaming bool
+	streaming, _ = ctx.Value(streamingResponseKey{}).(bool)
+	return streaming
+}
+
+// streamCall sends a call whose body is a RecordIO stream of calls read from
+// body, such as ATTACH_CONTAINER_INPUT. The body cannot be read twice, so the
+// call is neither retried nor redirected, and it does not pass through the
+// interceptors.
+func (c *client) streamCall(ctx context.Context, callType string, body io.Reader) (
+	httpRes *http.Response, err error,
+) {
+	var span trace.Span
+	ctx, span = c.startSpan(ctx, callType)
+	defer func() { endSpan(span, err) }()
+	var start time.Time = time.Now()
+	defer func() { c.metrics.observeCall(callType, c.target, time.Since(start), err) }()
+
+	var endpoint *url.URL = c.leader.get()
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPost, endpoint.String(), body)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", recordioContentType)
+	req.Header.Set("Message-Content-Type", c.encoding.contentType())
+	req.Header.Set("Accept", c.encoding.contentType())
+	err = c.setHeaders(ctx, req)
+	if err != nil {
+		return
+	}
+	httpRes, err = c.httpclient.Do(req.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	if httpRes.StatusCode > 299 || httpRes.StatusCode < 200 {
+		var msg []byte
+		msg, _ = ioutil.ReadAll(httpRes.Body)
+		httpRes.Body.Close()
+		err = HTTPError{StatusCode: httpRes.StatusCode, Body: string(msg), CallType: callType, Attempts: 1}
+	}
+	return
+}
+
+// makeCall sends inputMessage through the interceptors and decodes the response
+// into outputMessage. The returned httpResponse is never nil, so callers may
+// close its body even when the server could not be reached.
 func (c *client) makeCall(
 	ctx context.Context, inputMessage proto.Message, outputMessage proto.Message,
 ) (httpResponse *http.Response, err error) {
+	httpResponse, err = c.invoker(ctx, inputMessage, outputMessage)
+	if httpResponse == nil {
+		httpResponse = &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(nil))}
+	}
+	return
+}
+
+// invoke is the Invoker that marshals the call and sends it.
+func (c *client) invoke(
+	ctx context.Context, inputMessage proto.Message, outputMessage proto.Message,
+) (httpResponse *http.Response, err error) {
+	var callType string = callTypeOf(inputMessage)
+	var span trace.Span
+	ctx, span = c.startSpan(ctx, callType)
+	defer func() { endSpan(span, err) }()
+	var start time.Time = time.Now()
+	defer func() { c.metrics.observeCall(callType, c.target, time.Since(start), err) }()
+
 	var b []byte
-	b, err = proto.Marshal(inputMessage)
+	b, err = c.encoding.marshal(inputMessage)
 	if err != nil {
 		return
 	}
-	var buf io.Reader = bytes.NewBuffer(b)
-	httpResponse, err = c.doProtoWrapper(ctx, buf, outputMessage)
+	httpResponse, err = c.doProtoWrapper(ctx, callType, b, outputMessage)
 	return
 }
 
-// binaryExponentialBackoff is a stateful implementation of binary exponential
-// backoff
-type binaryExponentialBackoff struct {
-	// Estimated Round Trip Time to host
-	rtt *time.Duration
-}
-
-// wait for the amount of time specified by the binary exponential backoff
-// algorithm described in section 8.2.4 of RFC 2616
-func (b *binaryExponentialBackoff) wait(count int) {
-	var retryDuration time.Duration = time.Duration(math.Pow(2, float64(count)))
-	time.Sleep(*b.rtt * retryDuration)
+// callTypeOf returns the type of a master or agent call, e.g. GET_HEALTH.
+func callTypeOf(call proto.Message) string {
+	switch call := call.(type) {
+	case *mesos_v1_master.Call:
+		return call.GetType().String()
+	case *mesos_v1_agent.Call:
+		return call.GetType().String()
+	}
+	return ""
 }
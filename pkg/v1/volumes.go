@@ -24,9 +24,12 @@ package v1
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
 	"github.com/mesos/go-proto/mesos/v1/master"
 )
 
@@ -53,3 +56,107 @@ func (m *Master) DestroyVolumes(ctx context.Context, call *mesos_v1_master.Call_
 	defer httpResponse.Body.Close()
 	return
 }
+
+// GrowVolume grows a persistent volume by the disk resources in Addition, which
+// must have the same reservations and disk source as the volume. The request is
+// forwarded asynchronously to the Mesos agent where the volume is located.
+func (m *Master) GrowVolume(ctx context.Context, call *mesos_v1_master.Call_GrowVolume) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_GROW_VOLUME
+	var message proto.Message = &mesos_v1_master.Call{Type: &callType, GrowVolume: call}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// ShrinkVolume shrinks a persistent volume by the size in Subtract. The freed
+// disk space keeps the reservations of the volume. The request is forwarded
+// asynchronously to the Mesos agent where the volume is located.
+func (m *Master) ShrinkVolume(ctx context.Context, call *mesos_v1_master.Call_ShrinkVolume) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_SHRINK_VOLUME
+	var message proto.Message = &mesos_v1_master.Call{Type: &callType, ShrinkVolume: call}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// ResizeVolume resizes the persistent volume on the agent with the ID agentID
+// to size megabytes, calling GrowVolume or ShrinkVolume as needed. The volume
+// is the disk Resource of the volume as reported by the master, e.g. by
+// GetAgents. Only volumes on the root disk or on a PATH disk that are reserved
+// for a role can be resized. ResizeVolume does nothing if the volume already
+// has the given size.
+//
+// e.g.
+//
+// 	err = m.ResizeVolume(ctx, agent.GetAgentInfo().GetId(), volume, 20480)
+func (m *Master) ResizeVolume(
+	ctx context.Context, agentID *mesos_v1.AgentID, volume *mesos_v1.Resource, size float64,
+) (err error) {
+	err = validateResizableVolume(volume, size)
+	if err != nil {
+		return
+	}
+	var current float64 = volume.GetScalar().GetValue()
+	var delta float64 = size - current
+	switch {
+	case size > current:
+		var addition *mesos_v1.Resource = &mesos_v1.Resource{
+			ProviderId:   volume.ProviderId,
+			Name:         volume.Name,
+			Type:         volume.Type,
+			Scalar:       &mesos_v1.Value_Scalar{Value: &delta},
+			Role:         volume.Role,
+			Reservation:  volume.Reservation,
+			Reservations: volume.Reservations,
+		}
+		if volume.GetDisk().GetSource() != nil {
+			addition.Disk = &mesos_v1.Resource_DiskInfo{Source: volume.GetDisk().GetSource()}
+		}
+		err = m.GrowVolume(ctx, &mesos_v1_master.Call_GrowVolume{
+			AgentId: agentID, Volume: volume, Addition: addition,
+		})
+	case size < current:
+		delta = -delta
+		err = m.ShrinkVolume(ctx, &mesos_v1_master.Call_ShrinkVolume{
+			AgentId: agentID, Volume: volume, Subtract: &mesos_v1.Value_Scalar{Value: &delta},
+		})
+	}
+	return
+}
+
+// validateResizableVolume returns an error if volume is not a persistent
+// volume that Mesos can resize to size.
+func validateResizableVolume(volume *mesos_v1.Resource, size float64) (err error) {
+	if volume.GetName() != "disk" || volume.GetType() != mesos_v1.Value_SCALAR ||
+		volume.GetDisk().GetPersistence().GetId() == "" {
+		err = errors.New("volume must be a persistent volume")
+		return
+	}
+	var source *mesos_v1.Resource_DiskInfo_Source = volume.GetDisk().GetSource()
+	if source != nil && source.GetType() != mesos_v1.Resource_DiskInfo_Source_PATH {
+		err = fmt.Errorf("volume on a %s disk cannot be resized", source.GetType())
+		return
+	}
+	var role string = reservationRole(volume)
+	if role == "" || role == "*" {
+		err = errors.New("volume must be reserved for a role")
+		return
+	}
+	if size <= 0 {
+		err = errors.New("size must be greater than 0")
+	}
+	return
+}
+
+// reservationRole returns the role a resource is reserved for, that is the role
+// of its last reservation. Resources in the pre-reservation-refinement format
+// carry the role in the role field instead.
+func reservationRole(resource *mesos_v1.Resource) string {
+	var reservations []*mesos_v1.Resource_ReservationInfo = resource.GetReservations()
+	if len(reservations) > 0 {
+		return reservations[len(reservations)-1].GetRole()
+	}
+	return resource.GetRole()
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+// newVolume returns a persistent volume of size megabytes reserved for role.
+func newVolume(size float64, role string, source *mesos_v1.Resource_DiskInfo_Source) *mesos_v1.Resource {
+	name := "disk"
+	valueType := mesos_v1.Value_SCALAR
+	persistenceID := "volume-1"
+	reservationType := mesos_v1.Resource_ReservationInfo_DYNAMIC
+	return &mesos_v1.Resource{
+		Name:   &name,
+		Type:   &valueType,
+		Scalar: &mesos_v1.Value_Scalar{Value: &size},
+		Reservations: []*mesos_v1.Resource_ReservationInfo{
+			&mesos_v1.Resource_ReservationInfo{Type: &reservationType, Role: &role},
+		},
+		Disk: &mesos_v1.Resource_DiskInfo{
+			Persistence: &mesos_v1.Resource_DiskInfo_Persistence{Id: &persistenceID},
+			Source:      source,
+		},
+	}
+}
+
+func TestGrowVolume(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := "test-agent"
+	call := &mesos_v1_master.Call_GrowVolume{
+		AgentId:  &mesos_v1.AgentID{Value: &agentID},
+		Volume:   newVolume(1024, "storage", nil),
+		Addition: newVolume(1024, "storage", nil),
+	}
+
+	err := s.Master().GrowVolume(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestShrinkVolume(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := "test-agent"
+	subtract := 512.0
+	call := &mesos_v1_master.Call_ShrinkVolume{
+		AgentId:  &mesos_v1.AgentID{Value: &agentID},
+		Volume:   newVolume(1024, "storage", nil),
+		Subtract: &mesos_v1.Value_Scalar{Value: &subtract},
+	}
+
+	err := s.Master().ShrinkVolume(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResizeVolume(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	agentID := &mesos_v1.AgentID{Value: proto.String("test-agent")}
+	pathType := mesos_v1.Resource_DiskInfo_Source_PATH
+	path := &mesos_v1.Resource_DiskInfo_Source{Type: &pathType}
+	err := s.Master().ResizeVolume(s.Ctx(), agentID, newVolume(1024, "storage", path), 3072)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Master().ResizeVolume(s.Ctx(), agentID, newVolume(1024, "storage", nil), 256); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Master().ResizeVolume(s.Ctx(), agentID, newVolume(1024, "storage", nil), 1024); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := s.MasterCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	addition := calls[0].GetGrowVolume().GetAddition()
+	if calls[0].GetType() != mesos_v1_master.Call_GROW_VOLUME || addition.GetScalar().GetValue() != 2048 {
+		t.Errorf("expected GROW_VOLUME by 2048, got %s", calls[0])
+	}
+	if addition.GetDisk().GetSource().GetType() != pathType || addition.GetDisk().GetPersistence() != nil {
+		t.Errorf("expected the addition to be on the PATH disk without persistence, got %s", addition.GetDisk())
+	}
+	if reservationRole(addition) != "storage" {
+		t.Errorf("expected the addition to be reserved for storage, got %s", reservationRole(addition))
+	}
+	if calls[1].GetType() != mesos_v1_master.Call_SHRINK_VOLUME || calls[1].GetShrinkVolume().GetSubtract().GetValue() != 768 {
+		t.Errorf("expected SHRINK_VOLUME by 768, got %s", calls[1])
+	}
+}
+
+func TestResizeVolumeValidation(t *testing.T) {
+	m, err := NewMasterBuilder("http://127.0.0.1:5050").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mountType := mesos_v1.Resource_DiskInfo_Source_MOUNT
+	notPersistent := newVolume(1024, "storage", nil)
+	notPersistent.Disk = nil
+
+	tests := []struct {
+		name   string
+		volume *mesos_v1.Resource
+		size   float64
+	}{
+		{"not persistent", notPersistent, 2048},
+		{"mount disk", newVolume(1024, "storage", &mesos_v1.Resource_DiskInfo_Source{Type: &mountType}), 2048},
+		{"unreserved", newVolume(1024, "*", nil), 2048},
+		{"zero size", newVolume(1024, "storage", nil), 0},
+	}
+	for _, test := range tests {
+		if err = m.ResizeVolume(context.Background(), nil, test.volume, test.size); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
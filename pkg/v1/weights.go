@@ -24,8 +24,12 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sort"
 
+	"github.com/gogo/protobuf/proto"
+	"github.com/mesos/go-proto/mesos/v1"
 	"github.com/mesos/go-proto/mesos/v1/master"
 )
 
@@ -36,3 +40,44 @@ func (m *Master) GetWeights(ctx context.Context) (response *mesos_v1_master.Resp
 	defer httpResponse.Body.Close()
 	return
 }
+
+// UpdateWeights updates the weights of roles. Roles that are not part of the
+// call keep their weight.
+func (m *Master) UpdateWeights(ctx context.Context, call *mesos_v1_master.Call_UpdateWeights) (err error) {
+	var callType mesos_v1_master.Call_Type = mesos_v1_master.Call_UPDATE_WEIGHTS
+	var message proto.Message = &mesos_v1_master.Call{Type: &callType, UpdateWeights: call}
+	var httpResponse *http.Response
+	httpResponse, err = m.client.makeCall(ctx, message, nil)
+	defer httpResponse.Body.Close()
+	return
+}
+
+// UpdateRoleWeights updates the weights of roles from a map of role to weight
+// with UpdateWeights. Weights must be greater than 0.
+//
+// e.g.
+//
+// 	err = m.UpdateRoleWeights(ctx, map[string]float64{"analytics": 2, "web": 3.5})
+func (m *Master) UpdateRoleWeights(ctx context.Context, weights map[string]float64) (err error) {
+	var roles []string = make([]string, 0, len(weights))
+	for role, weight := range weights {
+		if role == "" || role == "*" {
+			err = fmt.Errorf("invalid role %q", role)
+			return
+		}
+		if weight <= 0 {
+			err = fmt.Errorf("weight of role %s must be greater than 0", role)
+			return
+		}
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	var weightInfos []*mesos_v1.WeightInfo = make([]*mesos_v1.WeightInfo, 0, len(roles))
+	for _, role := range roles {
+		var role string = role
+		var weight float64 = weights[role]
+		weightInfos = append(weightInfos, &mesos_v1.WeightInfo{Role: &role, Weight: &weight})
+	}
+	err = m.UpdateWeights(ctx, &mesos_v1_master.Call_UpdateWeights{WeightInfos: weightInfos})
+	return
+}
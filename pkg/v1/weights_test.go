@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/mesos/go-proto/mesos/v1"
+	"github.com/mesos/go-proto/mesos/v1/master"
+)
+
+func TestUpdateWeights(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	role := "analytics"
+	weight := 2.0
+	call := &mesos_v1_master.Call_UpdateWeights{
+		WeightInfos: []*mesos_v1.WeightInfo{
+			&mesos_v1.WeightInfo{Role: &role, Weight: &weight},
+		},
+	}
+
+	err := s.Master().UpdateWeights(s.Ctx(), call)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateRoleWeights(t *testing.T) {
+	s := NewTestProtobufServer(MasterClient)
+	defer s.Teardown()
+
+	s.Handle()
+
+	err := s.Master().UpdateRoleWeights(s.Ctx(), map[string]float64{"web": 3.5, "analytics": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := s.MasterCalls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(calls))
+	}
+	call := calls[0]
+	weightInfos := call.GetUpdateWeights().GetWeightInfos()
+	if call.GetType() != mesos_v1_master.Call_UPDATE_WEIGHTS || len(weightInfos) != 2 {
+		t.Fatalf("expected UPDATE_WEIGHTS with 2 weights, got %s", call)
+	}
+	if weightInfos[0].GetRole() != "analytics" || weightInfos[0].GetWeight() != 2 ||
+		weightInfos[1].GetRole() != "web" || weightInfos[1].GetWeight() != 3.5 {
+		t.Errorf("unexpected weights %v", weightInfos)
+	}
+
+	if err = s.Master().UpdateRoleWeights(s.Ctx(), map[string]float64{"web": 0}); err == nil {
+		t.Error("expected an error for a weight of 0")
+	}
+}
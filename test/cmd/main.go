@@ -11,45 +11,57 @@ import (
 	"github.com/miroswan/mesops/pkg/v1"
 )
 
+// printer prints the events it receives. Heartbeats and unknown events are
+// ignored by the embedded v1.NopEventHandler.
+type printer struct {
+	v1.NopEventHandler
+}
+
+func (printer) OnSubscribed(e *mesos_v1_master.Event_Subscribed) {
+	fmt.Println(e.GetGetState())
+}
+
+func (printer) OnTaskAdded(e *mesos_v1_master.Event_TaskAdded) {
+	fmt.Println(e.GetTask())
+}
+
+func (printer) OnTaskUpdated(e *mesos_v1_master.Event_TaskUpdated) {
+	fmt.Println(e.GetState())
+}
+
+func (printer) OnAgentAdded(e *mesos_v1_master.Event_AgentAdded) {
+	fmt.Println(e.GetAgent())
+}
+
+func (printer) OnAgentRemoved(e *mesos_v1_master.Event_AgentRemoved) {
+	fmt.Println(e.GetAgentId())
+}
+
+func (printer) OnFrameworkAdded(e *mesos_v1_master.Event_FrameworkAdded) {
+	fmt.Println(e.GetFramework())
+}
+
+func (printer) OnFrameworkUpdated(e *mesos_v1_master.Event_FrameworkUpdated) {
+	fmt.Println(e.GetFramework())
+}
+
+func (printer) OnFrameworkRemoved(e *mesos_v1_master.Event_FrameworkRemoved) {
+	fmt.Println(e.GetFrameworkInfo())
+}
+
+func (printer) OnUnknown(e *mesos_v1_master.Event) {
+	fmt.Println("Event unknown")
+}
+
 func main() {
 	client, err := v1.NewMasterBuilder("http://192.168.33.10:5050").Build()
 	if err != nil {
 		log.Fatal(err)
 	}
-	es := make(v1.EventStream, 0)
-	ctx, _ := context.WithTimeout(context.Background(), 60*time.Second)
-	go func() {
-		err := client.Subscribe(ctx, es)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	for {
-		select {
-		case <-ctx.Done():
-			break
-		case e := <-es:
-			switch e.GetType() {
-			case mesos_v1_master.Event_SUBSCRIBED:
-				fmt.Println(e.GetSubscribed().GetGetState())
-			case mesos_v1_master.Event_TASK_ADDED:
-				fmt.Println(e.GetTaskAdded().GetTask())
-			case mesos_v1_master.Event_TASK_UPDATED:
-				fmt.Println(e.GetTaskUpdated().GetState())
-			case mesos_v1_master.Event_AGENT_ADDED:
-				fmt.Println(e.GetAgentAdded().GetAgent())
-			case mesos_v1_master.Event_AGENT_REMOVED:
-				fmt.Println(e.GetAgentRemoved().GetAgentId())
-			case mesos_v1_master.Event_FRAMEWORK_ADDED:
-				fmt.Println(e.GetFrameworkAdded().GetFramework())
-			case mesos_v1_master.Event_FRAMEWORK_UPDATED:
-				fmt.Println(e.GetFrameworkUpdated().GetFramework())
-			case mesos_v1_master.Event_FRAMEWORK_REMOVED:
-				fmt.Println(e.GetFrameworkRemoved().GetFrameworkInfo())
-			case mesos_v1_master.Event_UNKNOWN:
-				fmt.Println("Event unknown")
-			}
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	err = v1.Dispatch(ctx, client, printer{})
+	if err != nil && err != context.DeadlineExceeded {
+		log.Fatal(err)
 	}
 }